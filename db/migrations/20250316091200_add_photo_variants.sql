@@ -0,0 +1,18 @@
+-- migrate:up
+CREATE TABLE photo_variants (
+    id VARCHAR(255) PRIMARY KEY,
+    photo_id VARCHAR(255) NOT NULL REFERENCES photos(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    format VARCHAR(16) NOT NULL,
+    src TEXT NOT NULL,
+    public_id VARCHAR(255),
+    width INT NOT NULL,
+    height INT NOT NULL,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX photo_variants_photo_id_idx ON photo_variants (photo_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS photo_variants;
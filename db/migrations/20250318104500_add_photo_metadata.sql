@@ -0,0 +1,8 @@
+-- migrate:up
+ALTER TABLE photos ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE portfolios ADD COLUMN metadata_settings JSONB NOT NULL DEFAULT '{"show_camera": true, "show_lens": true, "show_exposure": true, "show_captured_at": true, "show_location": false, "show_copyright": true, "keep_location": false}';
+
+-- migrate:down
+ALTER TABLE portfolios DROP COLUMN IF EXISTS metadata_settings;
+ALTER TABLE photos DROP COLUMN IF EXISTS metadata;
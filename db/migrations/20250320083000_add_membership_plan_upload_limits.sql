@@ -0,0 +1,10 @@
+-- migrate:up
+ALTER TABLE membership_plans ADD COLUMN max_upload_bytes BIGINT DEFAULT NULL;
+ALTER TABLE membership_plans ADD COLUMN max_image_dimension INT DEFAULT NULL;
+
+UPDATE membership_plans SET max_upload_bytes = 10485760, max_image_dimension = 6000 WHERE id = 'free';
+UPDATE membership_plans SET max_upload_bytes = 104857600, max_image_dimension = 12000 WHERE id IN ('monthly-unlimited', 'yearly-unlimited');
+
+-- migrate:down
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_image_dimension;
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_upload_bytes;
@@ -0,0 +1,16 @@
+-- migrate:up
+ALTER TABLE photos ADD COLUMN user_id VARCHAR(255) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE photos ADD COLUMN phash VARCHAR(16) NOT NULL DEFAULT '';
+
+UPDATE photos
+SET user_id = portfolios.user_id
+FROM folders
+JOIN portfolios ON portfolios.id = folders.portfolio_id
+WHERE folders.id = photos.folder_id;
+
+CREATE INDEX photos_user_id_idx ON photos (user_id);
+
+-- migrate:down
+DROP INDEX IF EXISTS photos_user_id_idx;
+ALTER TABLE photos DROP COLUMN IF EXISTS phash;
+ALTER TABLE photos DROP COLUMN IF EXISTS user_id;
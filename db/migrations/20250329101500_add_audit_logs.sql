@@ -0,0 +1,14 @@
+-- migrate:up
+CREATE TABLE audit_logs (
+    id VARCHAR(255) PRIMARY KEY,
+    actor_id VARCHAR(255) REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(64) NOT NULL,
+    target TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_logs_actor_id_idx ON audit_logs (actor_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS audit_logs;
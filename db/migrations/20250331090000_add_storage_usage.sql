@@ -0,0 +1,21 @@
+-- migrate:up
+ALTER TABLE membership_plans ADD COLUMN max_storage_bytes BIGINT DEFAULT NULL;
+ALTER TABLE membership_plans ADD COLUMN max_photos INT DEFAULT NULL;
+
+UPDATE membership_plans SET max_storage_bytes = 1073741824, max_photos = 500 WHERE id = 'free';
+UPDATE membership_plans SET max_storage_bytes = 107374182400 WHERE id IN ('monthly-unlimited', 'yearly-unlimited');
+
+ALTER TABLE photos ADD COLUMN bytes BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE storage_usages (
+    user_id VARCHAR(255) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    photos INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS storage_usages;
+ALTER TABLE photos DROP COLUMN IF EXISTS bytes;
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_photos;
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_storage_bytes;
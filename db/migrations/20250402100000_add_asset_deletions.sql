@@ -0,0 +1,16 @@
+-- migrate:up
+CREATE TABLE asset_deletions (
+    id VARCHAR(255) PRIMARY KEY,
+    public_id TEXT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX asset_deletions_status_next_attempt_at_idx ON asset_deletions (status, next_attempt_at);
+
+-- migrate:down
+DROP TABLE IF EXISTS asset_deletions;
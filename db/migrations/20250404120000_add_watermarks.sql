@@ -0,0 +1,20 @@
+-- migrate:up
+ALTER TABLE portfolios ADD COLUMN watermark_settings JSONB;
+
+ALTER TABLE photos ADD COLUMN original_src TEXT;
+ALTER TABLE photos ADD COLUMN render_requested_at TIMESTAMPTZ;
+ALTER TABLE photos ADD COLUMN render_attempts INT NOT NULL DEFAULT 0;
+
+UPDATE photos SET original_src = src;
+
+ALTER TABLE photo_variants ADD COLUMN watermarked BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX photos_render_requested_at_idx ON photos (render_requested_at) WHERE render_requested_at IS NOT NULL;
+
+-- migrate:down
+DROP INDEX IF EXISTS photos_render_requested_at_idx;
+ALTER TABLE photo_variants DROP COLUMN IF EXISTS watermarked;
+ALTER TABLE photos DROP COLUMN IF EXISTS render_attempts;
+ALTER TABLE photos DROP COLUMN IF EXISTS render_requested_at;
+ALTER TABLE photos DROP COLUMN IF EXISTS original_src;
+ALTER TABLE portfolios DROP COLUMN IF EXISTS watermark_settings;
@@ -0,0 +1,9 @@
+-- migrate:up
+ALTER TABLE photos ADD COLUMN blurhash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE photos ADD COLUMN dominant_color VARCHAR(7) NOT NULL DEFAULT '';
+ALTER TABLE photos ADD COLUMN average_color VARCHAR(7) NOT NULL DEFAULT '';
+
+-- migrate:down
+ALTER TABLE photos DROP COLUMN IF EXISTS average_color;
+ALTER TABLE photos DROP COLUMN IF EXISTS dominant_color;
+ALTER TABLE photos DROP COLUMN IF EXISTS blurhash;
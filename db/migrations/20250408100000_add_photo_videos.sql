@@ -0,0 +1,23 @@
+-- migrate:up
+ALTER TABLE photos ADD COLUMN media_type VARCHAR(10) NOT NULL DEFAULT 'image';
+ALTER TABLE photos ADD COLUMN width INT NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN height INT NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN duration_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN poster_src TEXT NOT NULL DEFAULT '';
+ALTER TABLE photos ADD COLUMN poster_public_id VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE membership_plans ADD COLUMN max_video_bytes BIGINT DEFAULT NULL;
+ALTER TABLE membership_plans ADD COLUMN max_video_seconds INT DEFAULT NULL;
+
+UPDATE membership_plans SET max_video_bytes = 52428800, max_video_seconds = 30 WHERE id = 'free';
+UPDATE membership_plans SET max_video_bytes = 524288000, max_video_seconds = 180 WHERE id IN ('monthly-unlimited', 'yearly-unlimited');
+
+-- migrate:down
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_video_seconds;
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_video_bytes;
+ALTER TABLE photos DROP COLUMN IF EXISTS poster_public_id;
+ALTER TABLE photos DROP COLUMN IF EXISTS poster_src;
+ALTER TABLE photos DROP COLUMN IF EXISTS duration_ms;
+ALTER TABLE photos DROP COLUMN IF EXISTS height;
+ALTER TABLE photos DROP COLUMN IF EXISTS width;
+ALTER TABLE photos DROP COLUMN IF EXISTS media_type;
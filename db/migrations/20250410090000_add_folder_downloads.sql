@@ -0,0 +1,5 @@
+-- migrate:up
+ALTER TABLE folders ADD COLUMN allow_download BOOLEAN NOT NULL DEFAULT false;
+
+-- migrate:down
+ALTER TABLE folders DROP COLUMN IF EXISTS allow_download;
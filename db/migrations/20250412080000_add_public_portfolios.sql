@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE portfolios ADD COLUMN published BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE profiles ADD COLUMN show_email BOOLEAN NOT NULL DEFAULT false;
+
+-- migrate:down
+ALTER TABLE profiles DROP COLUMN IF EXISTS show_email;
+ALTER TABLE portfolios DROP COLUMN IF EXISTS published;
@@ -0,0 +1,19 @@
+-- migrate:up
+ALTER TABLE users ADD COLUMN username VARCHAR(64) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX users_username_idx ON users (LOWER(username)) WHERE username <> '';
+
+CREATE TABLE username_history (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX username_history_username_idx ON username_history (LOWER(username), expires_at);
+CREATE INDEX username_history_user_id_idx ON username_history (user_id);
+
+-- migrate:down
+DROP TABLE IF EXISTS username_history;
+DROP INDEX IF EXISTS users_username_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
@@ -0,0 +1,22 @@
+-- migrate:up
+CREATE TABLE custom_domains (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hostname VARCHAR(253) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    failure_reason TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMPTZ,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT custom_domains_user_hostname_idx UNIQUE (user_id, hostname)
+);
+
+-- Many accounts may try a hostname, only one can prove it.
+CREATE UNIQUE INDEX custom_domains_verified_hostname_idx ON custom_domains (hostname) WHERE status = 'verified';
+CREATE INDEX custom_domains_status_idx ON custom_domains (status, checked_at);
+
+-- migrate:down
+DROP TABLE IF EXISTS custom_domains;
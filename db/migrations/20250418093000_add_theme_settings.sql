@@ -0,0 +1,10 @@
+-- migrate:up
+ALTER TABLE portfolios ADD COLUMN theme_settings JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE membership_plans ADD COLUMN premium_themes BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE membership_plans SET premium_themes = true WHERE id IN ('monthly-unlimited', 'yearly-unlimited');
+UPDATE portfolios SET theme = 'light' WHERE theme IS NULL OR theme NOT IN ('light', 'dark', 'minimal', 'editorial', 'noir');
+
+-- migrate:down
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS premium_themes;
+ALTER TABLE portfolios DROP COLUMN IF EXISTS theme_settings;
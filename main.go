@@ -1,18 +1,31 @@
 package main
 
 import (
+	"context"
+	"net"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/go-playground/validator"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/notblessy/ekspresi-core/db"
+	"github.com/notblessy/ekspresi-core/model"
 	"github.com/notblessy/ekspresi-core/repository"
 	"github.com/notblessy/ekspresi-core/router"
 	"github.com/notblessy/ekspresi-core/utils"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/notblessy/ekspresi-core/worker"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -32,19 +45,90 @@ func main() {
 			echo.HeaderAccept,
 			echo.HeaderAuthorization,
 			"X-Path",
+			"Tus-Resumable",
+			"Upload-Length",
+			"Upload-Metadata",
+			"Upload-Offset",
+		},
+		ExposeHeaders: []string{
+			echo.HeaderLocation,
+			"Tus-Resumable",
+			"Tus-Version",
+			"Tus-Extension",
+			"Upload-Offset",
+			"Upload-Length",
+			"Upload-Expires",
+			"Upload-Photo-ID",
 		},
 	}))
 	e.Use(middleware.CORS())
 	e.Validator = &utils.Ghost{Validator: validator.New()}
 
-	cloudinary, err := cloudinary.NewFromURL(os.Getenv("CLOUDINARY_URL"))
-	continueOrFatal(err)
-
 	userRepo := repository.NewUserRepository(postgres)
-	uploaderRepo := repository.NewUploaderRepository(cloudinary, postgres)
+	uploaderRepo := newUploaderRepository(e, postgres)
 	portfolioRepo := repository.NewPortfolioRepository(postgres, uploaderRepo)
 	membershipRepo := repository.NewMembershipRepository(postgres)
 	membershipPlanRepo := repository.NewMembershipPlanRepository(postgres)
+	importJobRepo := repository.NewImportJobRepository(postgres)
+	assetReconciler := repository.NewAssetReconciler(postgres, uploaderRepo, strings.Trim(path.Join(os.Getenv("UPLOADER_BASE_PATH"), "portfolios"), "/"), time.Hour)
+	uploadPurger := repository.NewUploadPurger(postgres, uploaderRepo)
+	usageRepo := repository.NewUsageRepository(postgres, uploaderRepo)
+	assetDeletionRepo := repository.NewAssetDeletionRepository(postgres, uploaderRepo)
+	ownershipRepo := repository.NewOwnershipRepository(postgres)
+	customDomainRepo := repository.NewCustomDomainRepository(postgres)
+	domainVerifier := repository.NewDomainVerifier(postgres, net.DefaultResolver)
+	uploadSessionRepo := repository.NewUploadSessionRepository(postgres, utils.GetEnv("TUS_STORAGE_PATH", filepath.Join(os.TempDir(), "ekspresi-tus")))
+
+	if len(os.Args) > 1 && os.Args[1] == "backfill-usage" {
+		sized, err := usageRepo.Backfill(context.Background())
+		continueOrFatal(err)
+
+		logrus.WithField("photos", sized).Info("backfilled storage usage")
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "backfill-placeholders" {
+		updated, err := repository.NewPlaceholderRepository(postgres, uploaderRepo).Backfill(context.Background())
+		continueOrFatal(err)
+
+		logrus.WithField("photos", updated).Info("backfilled photo placeholders")
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "backfill-phash" {
+		updated, err := repository.NewPhotoHashRepository(postgres, uploaderRepo).Backfill(context.Background())
+		continueOrFatal(err)
+
+		logrus.WithField("photos", updated).Info("backfilled photo hashes")
+		return
+	}
+
+	interrupted, err := importJobRepo.FailUnfinished(context.Background(), "interrupted by a server restart")
+	continueOrFatal(err)
+
+	if interrupted > 0 {
+		logrus.WithField("jobs", interrupted).Warn("failed interrupted import jobs")
+	}
+
+	imagePipeline, err := imaging.NewPipeline(
+		utils.GetEnv("IMAGE_VARIANTS", imaging.DefaultVariants),
+		utils.GetEnv("IMAGE_VARIANT_FORMATS", imaging.DefaultFormats),
+		imaging.DefaultQuality,
+	)
+	continueOrFatal(err)
+
+	variantRenderer := repository.NewVariantRenderer(postgres, uploaderRepo, imagePipeline)
+
+	usernameRedirectPeriod, err := time.ParseDuration(utils.GetEnv("USERNAME_REDIRECT_PERIOD", model.DefaultUsernameRedirectPeriod.String()))
+	continueOrFatal(err)
+
+	duplicateThreshold, err := strconv.Atoi(utils.GetEnv("DUPLICATE_HASH_THRESHOLD", strconv.Itoa(imaging.DefaultDuplicateThreshold)))
+	continueOrFatal(err)
+
+	duplicatePolicy := utils.GetEnv("DUPLICATE_POLICY", model.DuplicatePolicyWarn)
+	if duplicatePolicy != model.DuplicatePolicyWarn && duplicatePolicy != model.DuplicatePolicyBlock {
+		logrus.Fatalf("unknown duplicate policy: %s", duplicatePolicy)
+	}
 
 	httpService := router.NewHTTPService()
 	httpService.RegisterPostgres(postgres)
@@ -53,12 +137,89 @@ func main() {
 	httpService.RegisterMembershipPlanRepository(membershipPlanRepo)
 	httpService.RegisterUploaderRepository(uploaderRepo)
 	httpService.RegisterPortfolioRepository(portfolioRepo)
+	httpService.RegisterUploadSessionRepository(uploadSessionRepo)
+	httpService.RegisterImportJobRepository(importJobRepo)
+	httpService.RegisterAssetReconciler(assetReconciler)
+	httpService.RegisterUploadPurger(uploadPurger)
+	httpService.RegisterUsageRepository(usageRepo)
+	httpService.RegisterAssetDeletionRepository(assetDeletionRepo)
+	httpService.RegisterVariantRenderer(variantRenderer)
+	httpService.RegisterOwnershipRepository(ownershipRepo)
+	httpService.RegisterCustomDomainRepository(customDomainRepo)
+	httpService.RegisterDomainVerifier(domainVerifier)
+	httpService.RegisterUsernameRepository(repository.NewUsernameRepository(postgres, usernameRedirectPeriod))
+	httpService.RegisterDuplicateDetection(duplicateThreshold, duplicatePolicy)
+
+	go worker.NewUploadSessionCleaner(uploadSessionRepo, time.Hour).Start(context.Background())
+	go worker.NewAssetDeletionWorker(assetDeletionRepo, 10*time.Second, 100).Start(context.Background())
+	go worker.NewVariantRenderWorker(variantRenderer, httpService, 30*time.Second, 20).Start(context.Background())
+	go worker.NewDomainVerifyWorker(domainVerifier, 5*time.Minute, 50).Start(context.Background())
+
+	if interval := os.Getenv("RECONCILE_INTERVAL"); interval != "" {
+		reconcileInterval, err := time.ParseDuration(interval)
+		continueOrFatal(err)
+
+		go worker.NewAssetReconcileWorker(assetReconciler, httpService, reconcileInterval, os.Getenv("RECONCILE_DELETE") != "true").Start(context.Background())
+	}
+
+	e.Pre(router.NewCustomDomainMiddleware(customDomainRepo, strings.Split(utils.GetEnv("APP_HOSTS", "localhost"), ","), time.Minute).Resolve)
 
 	httpService.Router(e)
 
 	e.Logger.Fatal(e.Start(":3400"))
 }
 
+func newUploaderRepository(e *echo.Echo, postgres *gorm.DB) model.UploaderRepository {
+	assetURL := utils.GetEnv("APP_URL", "http://localhost:3400") + "/api/v1/assets"
+
+	switch driver := utils.GetEnv("STORAGE_DRIVER", model.StorageDriverCloudinary); driver {
+	case model.StorageDriverLocal:
+		dir := utils.GetEnv("LOCAL_STORAGE_PATH", "storage")
+		privateDir := utils.GetEnv("LOCAL_PRIVATE_STORAGE_PATH", "storage-private")
+
+		// Everything under dir is served as is.
+		if rel, err := filepath.Rel(dir, privateDir); err == nil && !strings.HasPrefix(rel, "..") {
+			logrus.Fatal("LOCAL_PRIVATE_STORAGE_PATH must be outside LOCAL_STORAGE_PATH")
+		}
+
+		e.Static("/files", dir)
+
+		return repository.NewLocalUploaderRepository(dir, privateDir, utils.GetEnv("APP_URL", "http://localhost:3400")+"/files", assetURL, postgres)
+	case model.StorageDriverS3:
+		client, err := minio.New(os.Getenv("S3_ENDPOINT"), &minio.Options{
+			Creds:        credentials.NewStaticV4(os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY"), ""),
+			Secure:       utils.GetEnv("S3_USE_SSL", "true") == "true",
+			Region:       os.Getenv("S3_REGION"),
+			BucketLookup: minio.BucketLookupPath,
+		})
+		continueOrFatal(err)
+
+		presignExpiry, err := time.ParseDuration(utils.GetEnv("S3_PRESIGN_EXPIRY", "1h"))
+		continueOrFatal(err)
+
+		// A public bucket would deliver unwatermarked originals too.
+		if os.Getenv("S3_PUBLIC_URL") != "" && os.Getenv("S3_PRIVATE_BUCKET") == "" {
+			logrus.Fatal("S3_PRIVATE_BUCKET is required when S3_PUBLIC_URL is set")
+		}
+
+		return repository.NewS3UploaderRepository(client, repository.S3UploaderConfig{
+			Bucket:        os.Getenv("S3_BUCKET"),
+			PublicURL:     os.Getenv("S3_PUBLIC_URL"),
+			AssetURL:      assetURL,
+			PrivateBucket: os.Getenv("S3_PRIVATE_BUCKET"),
+			PresignExpiry: presignExpiry,
+		}, postgres)
+	case model.StorageDriverCloudinary:
+		cloudinary, err := cloudinary.NewFromURL(os.Getenv("CLOUDINARY_URL"))
+		continueOrFatal(err)
+
+		return repository.NewUploaderRepository(cloudinary, assetURL, postgres)
+	default:
+		logrus.Fatalf("unknown storage driver: %s", driver)
+		return nil
+	}
+}
+
 func continueOrFatal(err error) {
 	if err != nil {
 		logrus.Fatal(err)
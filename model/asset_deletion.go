@@ -0,0 +1,44 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	AssetDeletionStatusPending = "pending"
+	AssetDeletionStatusDead    = "dead"
+)
+
+type AssetDeletionRepository interface {
+	Enqueue(ctx context.Context, publicIDs []string) error
+	Drain(ctx context.Context, limit int) (AssetDeletionResult, error)
+	Stats(ctx context.Context) (AssetDeletionStats, error)
+	RetryDead(ctx context.Context) (int64, error)
+}
+
+// AssetDeletion is a queued storage delete. Rows are removed once the asset is
+// deleted and parked as dead after too many failed attempts.
+type AssetDeletion struct {
+	ID            string    `json:"id"`
+	PublicID      string    `json:"public_id"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// AssetDeletionResult counts the outcome of one drained batch.
+type AssetDeletionResult struct {
+	Deleted int
+	Retried int
+	Dead    int
+}
+
+type AssetDeletionStats struct {
+	Pending       int64      `json:"pending"`
+	Dead          int64      `json:"dead"`
+	OldestPending *time.Time `json:"oldest_pending"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+const (
+	AuditActionUploadPurge = "upload.purge"
+)
+
+// AuditLog records who performed a destructive action and on what.
+type AuditLog struct {
+	ID        string                 `json:"id"`
+	ActorID   string                 `json:"actor_id"`
+	Action    string                 `json:"action"`
+	Target    string                 `json:"target"`
+	Details   map[string]interface{} `json:"details" gorm:"serializer:json"`
+	CreatedAt time.Time              `json:"created_at"`
+}
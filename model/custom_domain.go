@@ -0,0 +1,159 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/utils/nuller"
+	"github.com/oklog/ulid/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	DomainStatusPending     = "pending"
+	DomainStatusVerified    = "verified"
+	DomainStatusFailed      = "failed"
+	DomainStatusDeactivated = "deactivated"
+
+	// DomainRecordPrefix is prepended to the hostname to name the TXT record
+	// proving ownership.
+	DomainRecordPrefix = "_ekspresi-verification."
+	// DomainVerificationWindow is how long a pending domain is retried before
+	// it is marked failed.
+	DomainVerificationWindow = 72 * time.Hour
+)
+
+// CustomDomainRepository stores the hostnames users serve their portfolio on.
+type CustomDomainRepository interface {
+	Create(ctx context.Context, domain CustomDomain) error
+	FindByID(ctx context.Context, id string) (CustomDomain, error)
+	FindByUserID(ctx context.Context, userID string) ([]CustomDomain, error)
+	Delete(ctx context.Context, id string) error
+	// FindUsernameByHost returns the username behind a verified hostname
+	// whose owner is still entitled to custom domains.
+	FindUsernameByHost(ctx context.Context, host string) (string, error)
+}
+
+// DomainVerifier checks the TXT records of custom domains.
+type DomainVerifier interface {
+	Verify(ctx context.Context, domain CustomDomain) (CustomDomain, error)
+	VerifyPending(ctx context.Context, limit int) (int, error)
+	// DeactivateIneligible deactivates the domains of users whose plan no
+	// longer includes custom domains.
+	DeactivateIneligible(ctx context.Context) (int, error)
+}
+
+// TXTResolver looks up DNS TXT records. *net.Resolver satisfies it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type CustomDomain struct {
+	ID            string          `json:"id"`
+	UserID        string          `json:"user_id"`
+	Hostname      string          `json:"hostname"`
+	Token         string          `json:"token"`
+	Status        string          `json:"status"`
+	FailureReason string          `json:"failure_reason,omitempty"`
+	RecordName    string          `json:"record_name" gorm:"-"`
+	RecordValue   string          `json:"record_value" gorm:"-"`
+	CheckedAt     nuller.NullTime `json:"checked_at"`
+	VerifiedAt    nuller.NullTime `json:"verified_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func (d *CustomDomain) TableName() string {
+	return "custom_domains"
+}
+
+// AfterFind fills in the TXT record the user has to publish.
+func (d *CustomDomain) AfterFind(tx *gorm.DB) error {
+	d.RecordName = DomainRecordPrefix + d.Hostname
+	d.RecordValue = "ekspresi-verification=" + d.Token
+
+	return nil
+}
+
+type CustomDomainInput struct {
+	Hostname string `json:"hostname" validate:"required"`
+}
+
+// NewCustomDomain starts verification of hostname for a user.
+func NewCustomDomain(userID, hostname string) CustomDomain {
+	domain := CustomDomain{
+		ID:        ulid.Make().String(),
+		UserID:    userID,
+		Hostname:  hostname,
+		Token:     ulid.Make().String(),
+		Status:    DomainStatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	domain.AfterFind(nil)
+
+	return domain
+}
+
+// CheckOwnership looks for the verification TXT record of the domain.
+func (d CustomDomain) CheckOwnership(ctx context.Context, resolver TXTResolver) error {
+	records, err := resolver.LookupTXT(ctx, DomainRecordPrefix+d.Hostname)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDomainUnverified, err.Error())
+	}
+
+	want := "ekspresi-verification=" + d.Token
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: no TXT record %q on %s", ErrDomainUnverified, want, DomainRecordPrefix+d.Hostname)
+}
+
+// NormalizeHostname lowercases a hostname and drops a trailing dot, port or
+// scheme pasted along with it.
+func NormalizeHostname(hostname string) string {
+	hostname = strings.ToLower(strings.TrimSpace(hostname))
+	hostname = strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://")
+	hostname = strings.TrimSuffix(hostname, "/")
+
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = host
+	}
+
+	return strings.TrimSuffix(hostname, ".")
+}
+
+// ValidateHostname checks that a normalized hostname is a DNS name with at
+// least two labels rather than an IP address.
+func ValidateHostname(hostname string) error {
+	if len(hostname) > 253 || net.ParseIP(hostname) != nil {
+		return ErrInvalidHostname
+	}
+
+	labels := strings.Split(hostname, ".")
+	if len(labels) < 2 {
+		return ErrInvalidHostname
+	}
+
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return ErrInvalidHostname
+		}
+
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return ErrInvalidHostname
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+type stubResolver map[string][]string
+
+func (s stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := s[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return records, nil
+}
+
+func TestCheckOwnership(t *testing.T) {
+	domain := NewCustomDomain("user", "photos.example.com")
+
+	tests := []struct {
+		name     string
+		resolver stubResolver
+		wantErr  bool
+	}{
+		{name: "matching record", resolver: stubResolver{domain.RecordName: {"v=spf1 -all", " " + domain.RecordValue + " "}}},
+		{name: "other token", resolver: stubResolver{domain.RecordName: {"ekspresi-verification=other"}}, wantErr: true},
+		{name: "record on the hostname itself", resolver: stubResolver{domain.Hostname: {domain.RecordValue}}, wantErr: true},
+		{name: "no record", resolver: stubResolver{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := domain.CheckOwnership(context.Background(), tt.resolver)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrDomainUnverified) {
+				t.Errorf("got %v, want ErrDomainUnverified", err)
+			}
+		})
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	tests := map[string]bool{
+		NormalizeHostname("Photos.Example.com."):          true,
+		NormalizeHostname("https://www.example.com:443/"): true,
+		"localhost":               false,
+		"192.168.1.1":             false,
+		"-bad.example.com":        false,
+		"under_score.example.com": false,
+		"example..com":            false,
+	}
+
+	for hostname, valid := range tests {
+		if err := ValidateHostname(hostname); (err == nil) != valid {
+			t.Errorf("%q: got %v, want valid %v", hostname, err, valid)
+		}
+	}
+}
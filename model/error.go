@@ -7,4 +7,34 @@ var (
 	ErrInvalidAuthClaim = errors.New("invalid auth claim")
 	ErrRegisterRequired = errors.New("register required")
 	ErrForbidden        = errors.New("forbidden request")
+	ErrInvalidPublicID  = errors.New("invalid public id")
+
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrFileTooLarge         = errors.New("file too large")
+	ErrImageTooLarge        = errors.New("image dimensions too large")
+	ErrCorruptImage         = errors.New("corrupt or truncated image")
+	ErrCorruptVideo         = errors.New("corrupt or unreadable video")
+	ErrVideoTooLong         = errors.New("video too long")
+	ErrDuplicatePhoto       = errors.New("photo already exists in portfolio")
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+	ErrPhotoLimitReached    = errors.New("photo limit reached")
+
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+
+	ErrUsernameInvalid  = errors.New("username must be 3 to 30 lowercase letters, digits or single hyphens")
+	ErrUsernameReserved = errors.New("username is reserved")
+	ErrUsernameTaken    = errors.New("username is taken")
+
+	ErrInvalidHostname        = errors.New("invalid hostname")
+	ErrDomainTaken            = errors.New("domain is already verified by another account")
+	ErrDomainUnverified       = errors.New("domain ownership not verified")
+	ErrCustomDomainNotAllowed = errors.New("membership plan does not include custom domains")
+
+	ErrUnknownTheme      = errors.New("unknown theme")
+	ErrUnknownThemeToken = errors.New("unknown theme token")
+	ErrInvalidThemeToken = errors.New("invalid theme token")
+	ErrThemeNotAllowed   = errors.New("membership plan does not include this theme")
+
+	ErrAssetNotFound           = errors.New("asset not found")
+	ErrDirectUploadUnsupported = errors.New("direct uploads are not supported by this storage driver")
 )
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ImportJobStatusPending   = "pending"
+	ImportJobStatusRunning   = "running"
+	ImportJobStatusCompleted = "completed"
+	ImportJobStatusFailed    = "failed"
+)
+
+type ImportJobRepository interface {
+	Create(ctx context.Context, job ImportJob) error
+	FindByID(ctx context.Context, id string) (ImportJob, error)
+	Update(ctx context.Context, job ImportJob) error
+	// FailUnfinished marks pending and running jobs as failed with reason,
+	// for jobs whose goroutine died with a previous process.
+	FailUnfinished(ctx context.Context, reason string) (int64, error)
+}
+
+// ImportJob tracks a ZIP archive being unpacked into a folder.
+type ImportJob struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	FolderID  string    `json:"folder_id"`
+	Status    string    `json:"status"`
+	Total     int       `json:"total"`
+	Processed int       `json:"processed"`
+	Imported  int       `json:"imported"`
+	Skipped   int       `json:"skipped"`
+	Failed    int       `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
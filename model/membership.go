@@ -20,6 +20,7 @@ const (
 type MembershipRepository interface {
 	FindAll(ctx context.Context, query MembershipQueryInput) ([]Membership, int64, error)
 	FindByID(ctx context.Context, id string) (Membership, error)
+	FindActiveByUserID(ctx context.Context, userID string) (Membership, error)
 	Create(ctx context.Context, input Membership) error
 	Update(ctx context.Context, id string, input Membership) error
 	Delete(ctx context.Context, id string) error
@@ -88,7 +89,14 @@ type MembershipPlan struct {
 	Features          []string        `json:"features"`
 	IsPopular         bool            `json:"is_popular"`
 	MaxFolders        int             `json:"max_folders"`
+	MaxUploadBytes    int64           `json:"max_upload_bytes"`
+	MaxImageDimension int             `json:"max_image_dimension"`
+	MaxStorageBytes   int64           `json:"max_storage_bytes"`
+	MaxPhotos         int             `json:"max_photos"`
+	MaxVideoBytes     int64           `json:"max_video_bytes"`
+	MaxVideoSeconds   int             `json:"max_video_seconds"`
 	CustomDomain      bool            `json:"custom_domain"`
+	PremiumThemes     bool            `json:"premium_themes"`
 	AdvancedAnalytics bool            `json:"advanced_analytics"`
 	StripeProductID   string          `json:"stripe_product_id"`
 	CreatedAt         time.Time       `json:"created_at"`
@@ -102,7 +110,14 @@ type MembershipPlanInput struct {
 	Features          []string        `json:"features"`
 	IsPopular         bool            `json:"is_popular"`
 	MaxFolders        int             `json:"max_folders"`
+	MaxUploadBytes    int64           `json:"max_upload_bytes"`
+	MaxImageDimension int             `json:"max_image_dimension"`
+	MaxStorageBytes   int64           `json:"max_storage_bytes"`
+	MaxPhotos         int             `json:"max_photos"`
+	MaxVideoBytes     int64           `json:"max_video_bytes"`
+	MaxVideoSeconds   int             `json:"max_video_seconds"`
 	CustomDomain      bool            `json:"custom_domain"`
+	PremiumThemes     bool            `json:"premium_themes"`
 	AdvancedAnalytics bool            `json:"advanced_analytics"`
 	StripeProductID   string          `json:"stripe_product_id" validate:"required"`
 }
@@ -120,7 +135,14 @@ func (input MembershipPlanInput) ToMembershipPlan(id string) MembershipPlan {
 		Features:          input.Features,
 		IsPopular:         input.IsPopular,
 		MaxFolders:        input.MaxFolders,
+		MaxUploadBytes:    input.MaxUploadBytes,
+		MaxImageDimension: input.MaxImageDimension,
+		MaxStorageBytes:   input.MaxStorageBytes,
+		MaxPhotos:         input.MaxPhotos,
+		MaxVideoBytes:     input.MaxVideoBytes,
+		MaxVideoSeconds:   input.MaxVideoSeconds,
 		CustomDomain:      input.CustomDomain,
+		PremiumThemes:     input.PremiumThemes,
 		AdvancedAnalytics: input.AdvancedAnalytics,
 		StripeProductID:   input.StripeProductID,
 	}
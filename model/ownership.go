@@ -0,0 +1,21 @@
+package model
+
+import "context"
+
+// Resource kinds whose owner can be resolved. Assets are photos looked up by
+// public ID.
+const (
+	ResourcePortfolio = "portfolio"
+	ResourceProfile   = "profile"
+	ResourceFolder    = "folder"
+	ResourcePhoto     = "photo"
+	ResourceAsset     = "asset"
+	ResourceDomain    = "domain"
+)
+
+// OwnershipRepository resolves the user owning portfolios, profiles, folders,
+// photos and custom domains.
+type OwnershipRepository interface {
+	// FindOwners maps each id of kind that exists to its owner's user ID.
+	FindOwners(ctx context.Context, kind string, ids []string) (map[string]string, error)
+}
@@ -0,0 +1,9 @@
+package model
+
+import "context"
+
+// PhotoHashRepository fills in the perceptual hash of photos uploaded before
+// duplicate detection.
+type PhotoHashRepository interface {
+	Backfill(ctx context.Context) (int, error)
+}
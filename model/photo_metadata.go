@@ -0,0 +1,115 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// PhotoMetadata is the EXIF and IPTC data read from an uploaded photo.
+type PhotoMetadata struct {
+	CameraMake   string     `json:"camera_make,omitempty"`
+	CameraModel  string     `json:"camera_model,omitempty"`
+	Lens         string     `json:"lens,omitempty"`
+	FocalLength  float64    `json:"focal_length,omitempty"`
+	Aperture     float64    `json:"aperture,omitempty"`
+	ShutterSpeed string     `json:"shutter_speed,omitempty"`
+	ISO          int        `json:"iso,omitempty"`
+	CapturedAt   *time.Time `json:"captured_at,omitempty"`
+	Latitude     *float64   `json:"latitude,omitempty"`
+	Longitude    *float64   `json:"longitude,omitempty"`
+	Creator      string     `json:"creator,omitempty"`
+	Copyright    string     `json:"copyright,omitempty"`
+	Keywords     []string   `json:"keywords,omitempty"`
+}
+
+func (m PhotoMetadata) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *PhotoMetadata) Scan(value interface{}) error {
+	return scanJSON(value, m)
+}
+
+// Visible returns the metadata allowed by the portfolio settings.
+func (m PhotoMetadata) Visible(settings MetadataSettings) PhotoMetadata {
+	var visible PhotoMetadata
+
+	if settings.ShowCamera {
+		visible.CameraMake = m.CameraMake
+		visible.CameraModel = m.CameraModel
+	}
+
+	if settings.ShowLens {
+		visible.Lens = m.Lens
+	}
+
+	if settings.ShowExposure {
+		visible.FocalLength = m.FocalLength
+		visible.Aperture = m.Aperture
+		visible.ShutterSpeed = m.ShutterSpeed
+		visible.ISO = m.ISO
+	}
+
+	if settings.ShowCapturedAt {
+		visible.CapturedAt = m.CapturedAt
+	}
+
+	if settings.ShowLocation {
+		visible.Latitude = m.Latitude
+		visible.Longitude = m.Longitude
+	}
+
+	if settings.ShowCopyright {
+		visible.Creator = m.Creator
+		visible.Copyright = m.Copyright
+	}
+
+	visible.Keywords = m.Keywords
+
+	return visible
+}
+
+// MetadataSettings controls which photo metadata a portfolio publishes and
+// whether GPS data is kept in stored files.
+type MetadataSettings struct {
+	ShowCamera     bool `json:"show_camera"`
+	ShowLens       bool `json:"show_lens"`
+	ShowExposure   bool `json:"show_exposure"`
+	ShowCapturedAt bool `json:"show_captured_at"`
+	ShowLocation   bool `json:"show_location"`
+	ShowCopyright  bool `json:"show_copyright"`
+	KeepLocation   bool `json:"keep_location"`
+}
+
+func NewDefaultMetadataSettings() *MetadataSettings {
+	return &MetadataSettings{
+		ShowCamera:     true,
+		ShowLens:       true,
+		ShowExposure:   true,
+		ShowCapturedAt: true,
+		ShowCopyright:  true,
+	}
+}
+
+func (s MetadataSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *MetadataSettings) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+func scanJSON(value interface{}, dst interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return errors.New("unsupported json column type")
+	}
+}
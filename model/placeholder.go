@@ -0,0 +1,9 @@
+package model
+
+import "context"
+
+// PlaceholderRepository fills in the BlurHash and colours of photos uploaded
+// before they were computed.
+type PlaceholderRepository interface {
+	Backfill(ctx context.Context) (int, error)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/notblessy/ekspresi-core/utils/nuller"
 	"github.com/oklog/ulid/v2"
 )
 
@@ -13,20 +14,28 @@ const (
 
 type PortfolioRepository interface {
 	Patch(ctx context.Context, p PortfolioType) error
+	FindByID(ctx context.Context, id string) (Portfolio, error)
+	FindByUserID(ctx context.Context, userID string) (Portfolio, error)
+	FindFolderByID(ctx context.Context, id string) (Folder, error)
+	FindPublishedByUsername(ctx context.Context, username string) (PortfolioType, error)
 }
 
 type Portfolio struct {
-	ID             string    `json:"id"`
-	UserID         string    `json:"user_id"`
-	Title          string    `json:"title"`
-	Description    string    `json:"description"`
-	Theme          string    `json:"theme"`
-	Columns        int       `json:"columns"`
-	Gap            int       `json:"gap"`
-	RoundedCorners bool      `json:"rounded_corners"`
-	ShowCaptions   bool      `json:"show_captions"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                string             `json:"id"`
+	UserID            string             `json:"user_id"`
+	Title             string             `json:"title"`
+	Description       string             `json:"description"`
+	Theme             string             `json:"theme"`
+	ThemeSettings     ThemeSettings      `json:"theme_settings"`
+	Columns           int                `json:"columns"`
+	Gap               int                `json:"gap"`
+	RoundedCorners    bool               `json:"rounded_corners"`
+	ShowCaptions      bool               `json:"show_captions"`
+	MetadataSettings  *MetadataSettings  `json:"metadata_settings"`
+	WatermarkSettings *WatermarkSettings `json:"watermark_settings"`
+	Published         *bool              `json:"published" gorm:"default:false"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
 }
 
 type Profile struct {
@@ -38,12 +47,23 @@ type Profile struct {
 	Email       string `json:"email"`
 	Instagram   string `json:"instagram"`
 	Website     string `json:"website"`
+	ShowEmail   *bool  `json:"show_email" gorm:"default:false"`
+}
+
+// IsPublished reports whether visitors can see the portfolio.
+func (p *Portfolio) IsPublished() bool {
+	return p.Published != nil && *p.Published
 }
 
 func (p *Profile) TableName() string {
 	return "profiles"
 }
 
+// EmailVisible reports whether the email is shown on the public portfolio.
+func (p *Profile) EmailVisible() bool {
+	return p.ShowEmail != nil && *p.ShowEmail
+}
+
 type Folder struct {
 	ID             string    `json:"id"`
 	PortfolioID    string    `json:"portfolio_id"`
@@ -54,11 +74,18 @@ type Folder struct {
 	Gap            int       `json:"gap"`
 	ShowCaptions   bool      `json:"show_captions"`
 	RoundedCorners bool      `json:"rounded_corners"`
+	AllowDownload  *bool     `json:"allow_download" gorm:"default:false"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	Photos         []Photo   `json:"photos" gorm:"foreignKey:FolderID;references:ID"`
 }
 
+// DownloadAllowed reports whether visitors may download the folder as an
+// archive. Owners always can.
+func (f *Folder) DownloadAllowed() bool {
+	return f.AllowDownload != nil && *f.AllowDownload
+}
+
 func NewInitialFolder(portfolioID string) []Folder {
 	return []Folder{
 		{
@@ -95,20 +122,64 @@ func NewInitialFolder(portfolioID string) []Folder {
 }
 
 type Photo struct {
-	ID        string    `json:"id" form:"id"`
-	FolderID  string    `json:"folder_id" form:"folder_id"`
-	Src       string    `json:"src"`
-	Alt       string    `json:"alt" form:"alt"`
-	Caption   string    `json:"caption" form:"caption"`
-	PublicID  string    `json:"public_id"`
-	SortIndex int       `json:"sort_index" form:"sort_index"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                string          `json:"id" form:"id"`
+	UserID            string          `json:"user_id"`
+	FolderID          string          `json:"folder_id" form:"folder_id"`
+	Src               string          `json:"src"`
+	OriginalSrc       string          `json:"-"`
+	Alt               string          `json:"alt" form:"alt"`
+	Caption           string          `json:"caption" form:"caption"`
+	PublicID          string          `json:"public_id"`
+	MediaType         string          `json:"media_type"`
+	Width             int             `json:"width,omitempty"`
+	Height            int             `json:"height,omitempty"`
+	DurationMS        int64           `json:"duration_ms,omitempty" gorm:"column:duration_ms"`
+	PosterSrc         string          `json:"poster_src,omitempty"`
+	PosterPublicID    string          `json:"-"`
+	SortIndex         int             `json:"sort_index" form:"sort_index"`
+	Metadata          PhotoMetadata   `json:"metadata"`
+	PHash             string          `json:"phash" gorm:"column:phash"`
+	BlurHash          string          `json:"blurhash" gorm:"column:blurhash"`
+	DominantColor     string          `json:"dominant_color"`
+	AverageColor      string          `json:"average_color"`
+	Bytes             int64           `json:"bytes"`
+	RenderRequestedAt nuller.NullTime `json:"-"`
+	RenderAttempts    int             `json:"-"`
+	CreatedAt         time.Time       `json:"created_at"`
+	Variants          []PhotoVariant  `json:"variants" gorm:"foreignKey:PhotoID;references:ID"`
+	DuplicateOf       []string        `json:"duplicate_of,omitempty" gorm:"-"`
 }
 
 func (p *Photo) TableName() string {
 	return "photos"
 }
 
+// IsVideo reports whether the photo is a video clip. Src then points at the
+// clip and PosterSrc at a still frame.
+func (p *Photo) IsVideo() bool {
+	return p.MediaType == MediaTypeVideo
+}
+
+// PhotoVariant is a resized rendition of a photo, ordered by width so it can
+// be joined into a srcset.
+type PhotoVariant struct {
+	ID          string    `json:"id"`
+	PhotoID     string    `json:"photo_id"`
+	Name        string    `json:"name"`
+	Format      string    `json:"format"`
+	Src         string    `json:"src"`
+	PublicID    string    `json:"public_id"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Bytes       int64     `json:"bytes"`
+	Watermarked bool      `json:"watermarked"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (v *PhotoVariant) TableName() string {
+	return "photo_variants"
+}
+
 type FolderType struct {
 	Folder
 	Photos []Photo `json:"photos" gorm:"foreignKey:FolderID;references:ID"`
@@ -142,15 +213,20 @@ func (p *PortfolioType) TableName() string {
 
 func (pt *PortfolioType) GetPortfolio() Portfolio {
 	return Portfolio{
-		ID:             pt.ID,
-		UserID:         pt.UserID,
-		Title:          pt.Title,
-		Columns:        pt.Columns,
-		Gap:            pt.Gap,
-		RoundedCorners: pt.RoundedCorners,
-		ShowCaptions:   pt.ShowCaptions,
-		CreatedAt:      pt.CreatedAt,
-		UpdatedAt:      pt.UpdatedAt,
+		ID:                pt.ID,
+		UserID:            pt.UserID,
+		Title:             pt.Title,
+		Theme:             pt.Theme,
+		ThemeSettings:     pt.ThemeSettings,
+		Columns:           pt.Columns,
+		Gap:               pt.Gap,
+		RoundedCorners:    pt.RoundedCorners,
+		ShowCaptions:      pt.ShowCaptions,
+		MetadataSettings:  pt.MetadataSettings,
+		WatermarkSettings: pt.WatermarkSettings,
+		Published:         pt.Published,
+		CreatedAt:         pt.CreatedAt,
+		UpdatedAt:         pt.UpdatedAt,
 	}
 }
 
@@ -164,6 +240,7 @@ func (pt *PortfolioType) GetProfiles() Profile {
 		Email:       pt.Profiles.Email,
 		Instagram:   pt.Profiles.Instagram,
 		Website:     pt.Profiles.Website,
+		ShowEmail:   pt.Profiles.ShowEmail,
 	}
 }
 
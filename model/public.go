@@ -0,0 +1,173 @@
+package model
+
+import "time"
+
+// FeedInvalidator drops the cached sitemaps and feeds of a user's portfolio.
+type FeedInvalidator interface {
+	InvalidateFeeds(userID string)
+}
+
+// PublicPortfolio is a published portfolio as shown to visitors. It leaves
+// out storage IDs, owner and billing data, and settings that only matter to
+// the editor.
+type PublicPortfolio struct {
+	Username       string         `json:"username"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	Theme          string         `json:"theme"`
+	ThemeTokens    ThemeSettings  `json:"theme_tokens"`
+	Columns        int            `json:"columns"`
+	Gap            int            `json:"gap"`
+	RoundedCorners bool           `json:"rounded_corners"`
+	ShowCaptions   bool           `json:"show_captions"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Profiles       PublicProfile  `json:"profiles"`
+	Folders        []PublicFolder `json:"folders"`
+}
+
+type PublicProfile struct {
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	Bio       string `json:"bio"`
+	Email     string `json:"email,omitempty"`
+	Instagram string `json:"instagram"`
+	Website   string `json:"website"`
+}
+
+type PublicFolder struct {
+	ID             string        `json:"id"`
+	Name           string        `json:"name"`
+	Description    string        `json:"description"`
+	CoverID        int           `json:"cover_id"`
+	Columns        int           `json:"columns"`
+	Gap            int           `json:"gap"`
+	ShowCaptions   bool          `json:"show_captions"`
+	RoundedCorners bool          `json:"rounded_corners"`
+	AllowDownload  bool          `json:"allow_download"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Photos         []PublicPhoto `json:"photos"`
+}
+
+type PublicPhoto struct {
+	ID            string          `json:"id"`
+	Src           string          `json:"src"`
+	Alt           string          `json:"alt"`
+	Caption       string          `json:"caption"`
+	MediaType     string          `json:"media_type"`
+	Width         int             `json:"width,omitempty"`
+	Height        int             `json:"height,omitempty"`
+	DurationMS    int64           `json:"duration_ms,omitempty"`
+	PosterSrc     string          `json:"poster_src,omitempty"`
+	SortIndex     int             `json:"sort_index"`
+	Metadata      PhotoMetadata   `json:"metadata"`
+	BlurHash      string          `json:"blurhash"`
+	DominantColor string          `json:"dominant_color"`
+	AverageColor  string          `json:"average_color"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Variants      []PublicVariant `json:"variants"`
+}
+
+type PublicVariant struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Src    string `json:"src"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// NewPublicPortfolio strips a portfolio down to what visitors may see.
+// Photo metadata follows the portfolio settings, the email is only kept
+// when the profile opts in and the theme comes with every token resolved.
+func NewPublicPortfolio(username string, pt PortfolioType) PublicPortfolio {
+	settings := NewDefaultMetadataSettings()
+	if pt.MetadataSettings != nil {
+		settings = pt.MetadataSettings
+	}
+
+	theme, ok := FindTheme(pt.Theme)
+	if !ok {
+		theme, _ = FindTheme(DefaultTheme)
+	}
+
+	public := PublicPortfolio{
+		Username:       username,
+		Title:          pt.Title,
+		Description:    pt.Description,
+		Theme:          theme.Name,
+		ThemeTokens:    theme.Resolve(pt.ThemeSettings),
+		Columns:        pt.Columns,
+		Gap:            pt.Gap,
+		RoundedCorners: pt.RoundedCorners,
+		ShowCaptions:   pt.ShowCaptions,
+		UpdatedAt:      pt.UpdatedAt,
+		Profiles: PublicProfile{
+			Name:      pt.Profiles.Name,
+			Title:     pt.Profiles.Title,
+			Bio:       pt.Profiles.Bio,
+			Instagram: pt.Profiles.Instagram,
+			Website:   pt.Profiles.Website,
+		},
+		Folders: []PublicFolder{},
+	}
+
+	if pt.Profiles.EmailVisible() {
+		public.Profiles.Email = pt.Profiles.Email
+	}
+
+	for _, f := range pt.Folders {
+		folder := PublicFolder{
+			ID:             f.ID,
+			Name:           f.Name,
+			Description:    f.Description,
+			CoverID:        f.CoverID,
+			Columns:        f.Columns,
+			Gap:            f.Gap,
+			ShowCaptions:   f.ShowCaptions,
+			RoundedCorners: f.RoundedCorners,
+			AllowDownload:  f.DownloadAllowed(),
+			CreatedAt:      f.CreatedAt,
+			Photos:         []PublicPhoto{},
+		}
+
+		for _, p := range f.Photos {
+			folder.Photos = append(folder.Photos, newPublicPhoto(p, *settings))
+		}
+
+		public.Folders = append(public.Folders, folder)
+	}
+
+	return public
+}
+
+func newPublicPhoto(p Photo, settings MetadataSettings) PublicPhoto {
+	photo := PublicPhoto{
+		ID:            p.ID,
+		Src:           p.Src,
+		Alt:           p.Alt,
+		Caption:       p.Caption,
+		MediaType:     p.MediaType,
+		Width:         p.Width,
+		Height:        p.Height,
+		DurationMS:    p.DurationMS,
+		PosterSrc:     p.PosterSrc,
+		SortIndex:     p.SortIndex,
+		Metadata:      p.Metadata.Visible(settings),
+		BlurHash:      p.BlurHash,
+		DominantColor: p.DominantColor,
+		AverageColor:  p.AverageColor,
+		CreatedAt:     p.CreatedAt,
+		Variants:      []PublicVariant{},
+	}
+
+	for _, v := range p.Variants {
+		photo.Variants = append(photo.Variants, PublicVariant{
+			Name:   v.Name,
+			Format: v.Format,
+			Src:    v.Src,
+			Width:  v.Width,
+			Height: v.Height,
+		})
+	}
+
+	return photo
+}
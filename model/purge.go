@@ -0,0 +1,33 @@
+package model
+
+import (
+	"context"
+	"fmt"
+)
+
+type UploadPurger interface {
+	Preview(ctx context.Context, prefix string) (PurgeReport, error)
+	Purge(ctx context.Context, actorID, prefix string) (PurgeReport, error)
+}
+
+// PurgeRequest purges the caller's uploads, or the uploads of UserID when the
+// caller is an admin. Confirm must equal the phrase returned by a dry run.
+type PurgeRequest struct {
+	UserID  string `json:"user_id"`
+	DryRun  bool   `json:"dry_run"`
+	Confirm string `json:"confirm"`
+}
+
+// PurgeReport lists what a purge removes, or removed when DryRun is unset.
+type PurgeReport struct {
+	DryRun        bool   `json:"dry_run"`
+	Prefix        string `json:"prefix"`
+	Assets        int    `json:"assets"`
+	Photos        int64  `json:"photos"`
+	ConfirmPhrase string `json:"confirm_phrase"`
+}
+
+// PurgeConfirmPhrase is the text a caller must type to purge prefix.
+func PurgeConfirmPhrase(prefix string) string {
+	return fmt.Sprintf("PURGE %s", prefix)
+}
@@ -0,0 +1,22 @@
+package model
+
+import "context"
+
+type AssetReconciler interface {
+	Reconcile(ctx context.Context, dryRun bool) (ReconcileReport, error)
+}
+
+// ReconcileReport lists storage assets without a photo row and photo rows
+// whose asset is gone. Nothing is deleted when DryRun is set.
+type ReconcileReport struct {
+	DryRun        bool     `json:"dry_run"`
+	Prefix        string   `json:"prefix"`
+	Assets        int      `json:"assets"`
+	Rows          int      `json:"rows"`
+	OrphanAssets  []string `json:"orphan_assets"`
+	MissingAssets []string `json:"missing_assets"`
+	DeletedAssets int      `json:"deleted_assets"`
+	DeletedRows   int64    `json:"deleted_rows"`
+	// UserIDs own the deleted rows, so their cached feeds are stale.
+	UserIDs []string `json:"-"`
+}
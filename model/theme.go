@@ -0,0 +1,192 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+)
+
+const (
+	ThemeTokenColor   = "color"
+	ThemeTokenFont    = "font"
+	ThemeTokenSpacing = "spacing"
+	ThemeTokenLayout  = "layout"
+
+	DefaultTheme = "light"
+)
+
+var (
+	themeColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+	themeFonts   = []string{"Inter", "Lora", "Playfair Display", "Space Grotesk", "IBM Plex Mono", "system-ui"}
+	themeLayouts = []string{"grid", "masonry", "justified", "carousel"}
+)
+
+// ThemeToken is one customizable value of a theme. Fonts and layouts pick
+// from Options, spacings are whole pixels between Min and Max and colours
+// are hex "#rrggbb".
+type ThemeToken struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Default interface{} `json:"default"`
+	Options []string    `json:"options,omitempty"`
+	Min     int         `json:"min,omitempty"`
+	Max     int         `json:"max,omitempty"`
+}
+
+// Theme is a named look with the tokens a portfolio may override. Premium
+// themes need a plan with PremiumThemes.
+type Theme struct {
+	Name    string       `json:"name"`
+	Label   string       `json:"label"`
+	Premium bool         `json:"premium"`
+	Tokens  []ThemeToken `json:"tokens"`
+}
+
+// ThemeSettings maps token names to values, both for the overrides stored on
+// a portfolio and for a resolved theme.
+type ThemeSettings map[string]interface{}
+
+func (s ThemeSettings) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(s)
+}
+
+func (s *ThemeSettings) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+// themeTokens builds the token schema shared by the built-in themes from
+// their defaults.
+func themeTokens(background, surface, text, muted, accent, headingFont, bodyFont string, gap int, layout string) []ThemeToken {
+	return []ThemeToken{
+		{Name: "background", Type: ThemeTokenColor, Default: background},
+		{Name: "surface", Type: ThemeTokenColor, Default: surface},
+		{Name: "text", Type: ThemeTokenColor, Default: text},
+		{Name: "muted", Type: ThemeTokenColor, Default: muted},
+		{Name: "accent", Type: ThemeTokenColor, Default: accent},
+		{Name: "heading_font", Type: ThemeTokenFont, Default: headingFont, Options: themeFonts},
+		{Name: "body_font", Type: ThemeTokenFont, Default: bodyFont, Options: themeFonts},
+		{Name: "section_spacing", Type: ThemeTokenSpacing, Default: 48, Min: 0, Max: 160},
+		{Name: "gallery_gap", Type: ThemeTokenSpacing, Default: gap, Min: 0, Max: 64},
+		{Name: "layout", Type: ThemeTokenLayout, Default: layout, Options: themeLayouts},
+	}
+}
+
+var themes = []Theme{
+	{Name: "light", Label: "Light", Tokens: themeTokens("#ffffff", "#f5f5f5", "#111111", "#6b7280", "#2563eb", "Inter", "Inter", 16, "grid")},
+	{Name: "dark", Label: "Dark", Tokens: themeTokens("#0b0b0c", "#18181b", "#f4f4f5", "#a1a1aa", "#f59e0b", "Inter", "Inter", 16, "grid")},
+	{Name: "minimal", Label: "Minimal", Tokens: themeTokens("#fafaf9", "#fafaf9", "#1c1917", "#78716c", "#1c1917", "Space Grotesk", "Inter", 8, "masonry")},
+	{Name: "editorial", Label: "Editorial", Premium: true, Tokens: themeTokens("#fffdf8", "#f3efe6", "#1f1a17", "#7c6f64", "#9a3412", "Playfair Display", "Lora", 24, "justified")},
+	{Name: "noir", Label: "Noir", Premium: true, Tokens: themeTokens("#000000", "#0f0f0f", "#e5e5e5", "#737373", "#e5e5e5", "IBM Plex Mono", "Inter", 4, "carousel")},
+}
+
+// Themes lists the registered themes.
+func Themes() []Theme {
+	return themes
+}
+
+// FindTheme looks a theme up by name.
+func FindTheme(name string) (Theme, bool) {
+	for _, t := range themes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return Theme{}, false
+}
+
+// Validate rejects overrides naming tokens the theme does not have or with
+// values outside their token's type.
+func (t Theme) Validate(overrides ThemeSettings) error {
+	for name, value := range overrides {
+		token, ok := t.token(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownThemeToken, name)
+		}
+
+		if err := token.validate(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns every token of the theme with overrides applied. Invalid
+// overrides, such as ones left from a previous theme, fall back to the
+// default.
+func (t Theme) Resolve(overrides ThemeSettings) ThemeSettings {
+	resolved := ThemeSettings{}
+
+	for _, token := range t.Tokens {
+		resolved[token.Name] = token.Default
+
+		if value, ok := overrides[token.Name]; ok && token.validate(value) == nil {
+			if token.Type == ThemeTokenSpacing {
+				value = int(value.(float64))
+			}
+
+			resolved[token.Name] = value
+		}
+	}
+
+	return resolved
+}
+
+func (t Theme) token(name string) (ThemeToken, bool) {
+	for _, token := range t.Tokens {
+		if token.Name == name {
+			return token, true
+		}
+	}
+
+	return ThemeToken{}, false
+}
+
+// validate checks a value decoded from JSON, where numbers are float64.
+func (tt ThemeToken) validate(value interface{}) error {
+	invalid := fmt.Errorf("%w: %s must be a %s", ErrInvalidThemeToken, tt.Name, tt.Type)
+
+	switch tt.Type {
+	case ThemeTokenColor:
+		s, ok := value.(string)
+		if !ok || !themeColorPattern.MatchString(s) {
+			return invalid
+		}
+	case ThemeTokenFont, ThemeTokenLayout:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(tt.Options, s) {
+			return invalid
+		}
+	case ThemeTokenSpacing:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < float64(tt.Min) || n > float64(tt.Max) {
+			return fmt.Errorf("%w: %s must be a whole number from %d to %d", ErrInvalidThemeToken, tt.Name, tt.Min, tt.Max)
+		}
+	default:
+		return invalid
+	}
+
+	return nil
+}
+
+// AllowsTheme reports whether the plan may use a theme.
+func (p MembershipPlan) AllowsTheme(t Theme) bool {
+	return !t.Premium || p.PremiumThemes
+}
+
+// ThemeListing is a theme with the IDs of the plans allowing it and whether
+// the current user's plan does.
+type ThemeListing struct {
+	Theme
+	Plans   []string `json:"plans"`
+	Allowed bool     `json:"allowed"`
+}
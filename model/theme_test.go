@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestThemeValidate(t *testing.T) {
+	theme, _ := FindTheme(DefaultTheme)
+
+	tests := []struct {
+		name      string
+		overrides string
+		want      error
+	}{
+		{name: "empty", overrides: `{}`},
+		{name: "valid", overrides: `{"accent":"#FF0066","body_font":"Lora","gallery_gap":24,"layout":"masonry"}`},
+		{name: "unknown token", overrides: `{"glow":"#ffffff"}`, want: ErrUnknownThemeToken},
+		{name: "short colour", overrides: `{"accent":"#fff"}`, want: ErrInvalidThemeToken},
+		{name: "unlisted font", overrides: `{"body_font":"Comic Sans MS"}`, want: ErrInvalidThemeToken},
+		{name: "fractional spacing", overrides: `{"gallery_gap":12.5}`, want: ErrInvalidThemeToken},
+		{name: "spacing out of range", overrides: `{"gallery_gap":500}`, want: ErrInvalidThemeToken},
+		{name: "spacing as string", overrides: `{"gallery_gap":"12"}`, want: ErrInvalidThemeToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var overrides ThemeSettings
+			if err := json.Unmarshal([]byte(tt.overrides), &overrides); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := theme.Validate(overrides); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestThemeResolve(t *testing.T) {
+	theme, _ := FindTheme("dark")
+
+	var overrides ThemeSettings
+	json.Unmarshal([]byte(`{"accent":"#ff0066","gallery_gap":24,"layout":"nope"}`), &overrides)
+
+	resolved := theme.Resolve(overrides)
+
+	if len(resolved) != len(theme.Tokens) {
+		t.Errorf("got %d tokens, want %d", len(resolved), len(theme.Tokens))
+	}
+
+	if resolved["accent"] != "#ff0066" || resolved["gallery_gap"] != 24 {
+		t.Errorf("overrides not applied: %v", resolved)
+	}
+
+	if resolved["layout"] != "grid" || resolved["background"] != "#0b0b0c" {
+		t.Errorf("defaults not kept: %v", resolved)
+	}
+}
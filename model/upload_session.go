@@ -0,0 +1,55 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/utils/nuller"
+)
+
+const TusVersion = "1.0.0"
+
+type UploadSessionRepository interface {
+	Create(ctx context.Context, session UploadSession) error
+	FindByID(ctx context.Context, id string) (UploadSession, error)
+	Append(ctx context.Context, session UploadSession, chunk io.Reader, expiresAt time.Time) (int64, error)
+	ReadAll(ctx context.Context, id string) ([]byte, error)
+	Complete(ctx context.Context, id, photoID string) error
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// UploadSession tracks a resumable tus upload. Chunks are appended to a file
+// on local disk and Offset is the number of bytes received so far.
+type UploadSession struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"user_id"`
+	Length      int64             `json:"length"`
+	Offset      int64             `json:"offset" gorm:"column:upload_offset"`
+	Metadata    map[string]string `json:"metadata" gorm:"serializer:json"`
+	PhotoID     string            `json:"photo_id"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	CompletedAt nuller.NullTime   `json:"completed_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func (s UploadSession) IsComplete() bool {
+	return s.Offset == s.Length
+}
+
+// Photo builds the photo fields sent as tus Upload-Metadata.
+func (s UploadSession) Photo() Photo {
+	var sortIndex int
+	fmt.Sscan(s.Metadata["sort_index"], &sortIndex)
+
+	return Photo{
+		ID:        s.Metadata["id"],
+		FolderID:  s.Metadata["folder_id"],
+		Alt:       s.Metadata["alt"],
+		Caption:   s.Metadata["caption"],
+		SortIndex: sortIndex,
+	}
+}
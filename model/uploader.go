@@ -3,16 +3,81 @@ package model
 import (
 	"context"
 	"io"
+	"time"
+)
+
+const (
+	StorageDriverCloudinary = "cloudinary"
+	StorageDriverLocal      = "local"
+	StorageDriverS3         = "s3"
+
+	DuplicatePolicyWarn  = "warn"
+	DuplicatePolicyBlock = "block"
+
+	MediaTypeImage = "image"
+	MediaTypeVideo = "video"
 )
 
 type UploaderRepository interface {
 	Upload(ctx context.Context, file io.Reader, path string) (string, string, error)
+	// UploadPrivate stores a file that storage never delivers on its own,
+	// such as the unwatermarked original of a photo. The returned URL is the
+	// asset route, which checks access and streams the file through Open.
+	UploadPrivate(ctx context.Context, file io.Reader, path string) (string, string, error)
+	// UploadVariant stores an already encoded rendition as is, keeping its
+	// format.
+	UploadVariant(ctx context.Context, file io.Reader, path, format string) (string, string, error)
+	UploadVideo(ctx context.Context, file io.Reader, path string) (string, string, error)
 	DeleteByPublicIDs(ctx context.Context, publicID []string) error
-	Flush(ctx context.Context) error
+	DeleteByPrefix(ctx context.Context, prefix string) error
+	URL(ctx context.Context, publicID string) (string, error)
+	SignUpload(ctx context.Context, path string, expiry time.Duration) (SignedUpload, error)
+	FindAsset(ctx context.Context, publicID string) (Asset, error)
+	List(ctx context.Context, prefix string) ([]Asset, error)
+	Open(ctx context.Context, publicID string) (io.ReadCloser, error)
 	FindByPublicIDs(ctx context.Context, publicIDs []string) ([]Photo, error)
 	SavePhoto(ctx context.Context, photo Photo) error
+	AppendPhoto(ctx context.Context, photo Photo) error
+	ReplacePhoto(ctx context.Context, photo Photo) error
+	FindByID(ctx context.Context, id string) (Photo, error)
+	FindHashedByUserID(ctx context.Context, userID string) ([]Photo, error)
+	FindByFolderID(ctx context.Context, folderID string) ([]Photo, error)
+	// IsPublishedAsset reports whether publicID is delivered by a photo of a
+	// published portfolio: its image, poster or one of its variants.
+	IsPublishedAsset(ctx context.Context, publicID string) (bool, error)
 }
 
 type DeleteRequest struct {
 	PublicIDs []string `json:"public_ids"`
 }
+
+// SignedUpload lets a client send a file straight to storage. Fields are sent
+// as multipart form values, Headers with a raw body. PublicID is set when the
+// backend fixes the asset name up front.
+type SignedUpload struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	PublicID  string            `json:"public_id,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Asset describes a stored file.
+type Asset struct {
+	PublicID  string    `json:"public_id"`
+	URL       string    `json:"url"`
+	Bytes     int64     `json:"bytes"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConfirmUploadRequest struct {
+	PublicID  string `json:"public_id" validate:"required"`
+	ID        string `json:"id"`
+	FolderID  string `json:"folder_id"`
+	Alt       string `json:"alt"`
+	Caption   string `json:"caption"`
+	SortIndex int    `json:"sort_index"`
+}
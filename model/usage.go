@@ -0,0 +1,38 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type UsageRepository interface {
+	FindByUserID(ctx context.Context, userID string) (StorageUsage, error)
+	Backfill(ctx context.Context) (int, error)
+}
+
+// StorageUsage is the running total of what a user stores, counting each
+// photo's original and variants.
+type StorageUsage struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey"`
+	Bytes     int64     `json:"bytes"`
+	Photos    int       `json:"photos"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UsageSummary compares usage with the plan limits. Zero limits are
+// unlimited.
+type UsageSummary struct {
+	BytesUsed     int64 `json:"bytes_used"`
+	BytesAllowed  int64 `json:"bytes_allowed"`
+	PhotosUsed    int   `json:"photos_used"`
+	PhotosAllowed int   `json:"photos_allowed"`
+}
+
+func NewUsageSummary(usage StorageUsage, plan MembershipPlan) UsageSummary {
+	return UsageSummary{
+		BytesUsed:     usage.Bytes,
+		BytesAllowed:  plan.MaxStorageBytes,
+		PhotosUsed:    usage.Photos,
+		PhotosAllowed: plan.MaxPhotos,
+	}
+}
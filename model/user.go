@@ -22,6 +22,7 @@ type UserRepository interface {
 type User struct {
 	ID        string         `json:"id"`
 	Email     string         `json:"email"`
+	Username  string         `json:"username"`
 	Name      string         `json:"name"`
 	Password  string         `json:"password,omitempty"`
 	Picture   string         `json:"picture"`
@@ -51,15 +52,16 @@ func (u *User) NewFreeMembership() Membership {
 
 func (u *User) NewInitialPortfolio() *Portfolio {
 	return &Portfolio{
-		ID:             ulid.Make().String(),
-		UserID:         u.ID,
-		Title:          "My Photography Portfolio",
-		Description:    "A showcase of my photography work and projects.",
-		Theme:          "light",
-		Columns:        3,
-		Gap:            16,
-		RoundedCorners: true,
-		ShowCaptions:   true,
+		ID:               ulid.Make().String(),
+		UserID:           u.ID,
+		Title:            "My Photography Portfolio",
+		Description:      "A showcase of my photography work and projects.",
+		Theme:            "light",
+		Columns:          3,
+		Gap:              16,
+		RoundedCorners:   true,
+		ShowCaptions:     true,
+		MetadataSettings: NewDefaultMetadataSettings(),
 	}
 }
 
@@ -83,7 +85,7 @@ type AuthRequest struct {
 }
 
 type ChangeUsernameRequest struct {
-	Username string `json:"username"`
+	Username string `json:"username" validate:"required"`
 }
 
 type GoogleAuthInfo struct {
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultUsernameRedirectPeriod is how long a released username redirects
+// to its owner's new one and stays reserved for them.
+const DefaultUsernameRedirectPeriod = 90 * 24 * time.Hour
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 30
+)
+
+// usernamePattern allows lowercase letters, digits and single hyphens,
+// starting and ending with a letter or digit.
+var usernamePattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// reservedUsernames would clash with routes, subdomains or the brand.
+var reservedUsernames = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true,
+	"api": true, "app": true, "assets": true, "auth": true, "billing": true,
+	"blog": true, "cdn": true, "contact": true, "dashboard": true, "docs": true,
+	"ekspresi": true, "explore": true, "favicon": true, "feed": true,
+	"files": true, "health": true, "help": true, "home": true, "login": true,
+	"logout": true, "mail": true, "me": true, "null": true, "ping": true,
+	"portfolio": true, "portfolios": true, "pricing": true, "privacy": true,
+	"public": true, "register": true, "robots": true, "root": true, "rss": true,
+	"settings": true, "signin": true, "signup": true, "sitemap": true,
+	"static": true, "status": true, "support": true, "system": true,
+	"terms": true, "undefined": true, "user": true, "users": true, "www": true,
+}
+
+// UsernameRepository claims usernames and resolves released ones.
+type UsernameRepository interface {
+	Change(ctx context.Context, userID, username string) (User, error)
+	FindRedirect(ctx context.Context, username string) (string, error)
+}
+
+// UsernameHistory records a username its owner gave up. Until ExpiresAt it
+// redirects to the owner's current username and only they can claim it.
+type UsernameHistory struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (h *UsernameHistory) TableName() string {
+	return "username_history"
+}
+
+// NormalizeUsername lowercases a username so lookups and uniqueness are
+// case-insensitive.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ValidateUsername checks a normalized username against the slug rules and
+// the reserved list.
+func ValidateUsername(username string) error {
+	if len(username) < UsernameMinLength || len(username) > UsernameMaxLength || !usernamePattern.MatchString(username) {
+		return ErrUsernameInvalid
+	}
+
+	if reservedUsernames[username] {
+		return ErrUsernameReserved
+	}
+
+	return nil
+}
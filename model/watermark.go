@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+)
+
+const (
+	WatermarkPositionTopLeft     = "top_left"
+	WatermarkPositionTopRight    = "top_right"
+	WatermarkPositionBottomLeft  = "bottom_left"
+	WatermarkPositionBottomRight = "bottom_right"
+	WatermarkPositionCenter      = "center"
+)
+
+// VariantRenderer renders and uploads the variants of a photo.
+type VariantRenderer interface {
+	Render(ctx context.Context, buf []byte, folder, photoID string, watermark *WatermarkSettings) ([]PhotoVariant, error)
+	// RenderPending returns the photos it re-rendered.
+	RenderPending(ctx context.Context, limit int) ([]Photo, error)
+}
+
+// WatermarkSettings describes the text or logo stamped on a portfolio's
+// variants. Scale is the watermark width as a fraction of the variant width.
+// An empty Variants list applies the watermark to every variant.
+type WatermarkSettings struct {
+	Enabled      bool     `json:"enabled"`
+	Text         string   `json:"text"`
+	LogoPublicID string   `json:"logo_public_id"`
+	Position     string   `json:"position" validate:"omitempty,oneof=top_left top_right bottom_left bottom_right center"`
+	Opacity      float32  `json:"opacity" validate:"gte=0,lte=1"`
+	Scale        float64  `json:"scale" validate:"gte=0,lte=1"`
+	Variants     []string `json:"variants"`
+}
+
+func (s WatermarkSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *WatermarkSettings) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+// Active reports whether there is anything to stamp.
+func (s *WatermarkSettings) Active() bool {
+	return s != nil && s.Enabled && (s.Text != "" || s.LogoPublicID != "")
+}
+
+// SameRender reports whether variants rendered with s and other look the
+// same, so switching between them needs no re-render.
+func (s *WatermarkSettings) SameRender(other *WatermarkSettings) bool {
+	if !s.Active() || !other.Active() {
+		return s.Active() == other.Active()
+	}
+
+	return reflect.DeepEqual(s, other)
+}
+
+// AppliesTo reports whether the named variant gets the watermark.
+func (s *WatermarkSettings) AppliesTo(variant string) bool {
+	if !s.Active() {
+		return false
+	}
+
+	if len(s.Variants) == 0 {
+		return true
+	}
+
+	for _, v := range s.Variants {
+		if v == variant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PublicSrc returns the URL a photo is delivered from. When variants are
+// watermarked the widest of them stands in for the original, which stays
+// private.
+func PublicSrc(original string, variants []PhotoVariant) string {
+	src := original
+	width := -1
+
+	for _, v := range variants {
+		if v.Watermarked && v.Width > width {
+			src = v.Src
+			width = v.Width
+		}
+	}
+
+	return src
+}
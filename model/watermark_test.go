@@ -0,0 +1,35 @@
+package model
+
+import "testing"
+
+func TestWatermarkSameRender(t *testing.T) {
+	stamp := &WatermarkSettings{Enabled: true, Text: "© Jane", Position: WatermarkPositionBottomRight, Opacity: 0.5, Scale: 0.2}
+
+	moved := *stamp
+	moved.Position = WatermarkPositionCenter
+
+	disabled := *stamp
+	disabled.Enabled = false
+
+	tests := []struct {
+		name   string
+		stored *WatermarkSettings
+		input  *WatermarkSettings
+		want   bool
+	}{
+		{name: "unchanged", stored: stamp, input: &WatermarkSettings{Enabled: true, Text: "© Jane", Position: WatermarkPositionBottomRight, Opacity: 0.5, Scale: 0.2}, want: true},
+		{name: "moved", stored: stamp, input: &moved},
+		{name: "enabled", stored: nil, input: stamp},
+		{name: "disabled", stored: stamp, input: &disabled},
+		{name: "disabled edit", stored: &disabled, input: &WatermarkSettings{Text: "other"}, want: true},
+		{name: "first disabled settings", stored: nil, input: &disabled, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.SameRender(tt.stored); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	assetDeletionMaxAttempts = 10
+	assetDeletionBaseBackoff = 30 * time.Second
+	assetDeletionMaxBackoff  = 6 * time.Hour
+)
+
+type assetDeletionRepository struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+}
+
+// NewAssetDeletionRepository queues storage deletes in postgres and performs
+// them through uploaderRepo.
+func NewAssetDeletionRepository(db *gorm.DB, uploaderRepo model.UploaderRepository) model.AssetDeletionRepository {
+	return &assetDeletionRepository{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+	}
+}
+
+func (r *assetDeletionRepository) Enqueue(ctx context.Context, publicIDs []string) error {
+	if err := enqueueAssetDeletions(r.db.WithContext(ctx), publicIDs); err != nil {
+		logrus.WithField("public_ids", publicIDs).WithError(err).Error("failed to enqueue asset deletions")
+		return err
+	}
+
+	return nil
+}
+
+// Drain deletes up to limit due assets in one storage call, falling back to
+// one call per asset when the batch fails. Rows are locked with SKIP LOCKED
+// so several workers can drain concurrently.
+func (r *assetDeletionRepository) Drain(ctx context.Context, limit int) (model.AssetDeletionResult, error) {
+	var result model.AssetDeletionResult
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var deletions []model.AssetDeletion
+
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.AssetDeletionStatusPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&deletions).Error; err != nil {
+			return err
+		}
+
+		if len(deletions) == 0 {
+			return nil
+		}
+
+		var publicIDs []string
+
+		for _, d := range deletions {
+			publicIDs = append(publicIDs, d.PublicID)
+		}
+
+		failed := map[string]error{}
+
+		// One bad key fails the whole batch, so a failed batch is retried
+		// one asset at a time to find the culprits.
+		if err := r.uploaderRepo.DeleteByPublicIDs(ctx, publicIDs); err != nil {
+			logrus.WithField("public_ids", publicIDs).WithError(err).Warn("failed to delete assets, retrying one by one")
+
+			for _, d := range deletions {
+				if err := r.uploaderRepo.DeleteByPublicIDs(ctx, []string{d.PublicID}); err != nil {
+					failed[d.ID] = err
+				}
+			}
+		}
+
+		var deleted []string
+
+		for _, d := range deletions {
+			deleteErr, ok := failed[d.ID]
+			if !ok {
+				deleted = append(deleted, d.ID)
+				continue
+			}
+
+			logrus.WithField("public_id", d.PublicID).WithError(deleteErr).Warn("failed to delete asset")
+
+			d.Attempts++
+			d.LastError = deleteErr.Error()
+			d.NextAttemptAt = time.Now().Add(assetDeletionBackoff(d.Attempts))
+			d.UpdatedAt = time.Now()
+
+			if d.Attempts >= assetDeletionMaxAttempts {
+				d.Status = model.AssetDeletionStatusDead
+				result.Dead++
+			} else {
+				result.Retried++
+			}
+
+			if err := tx.Save(&d).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(deleted) > 0 {
+			result.Deleted = len(deleted)
+			return tx.Where("id IN ?", deleted).Delete(&model.AssetDeletion{}).Error
+		}
+
+		return nil
+	})
+	if err != nil {
+		logrus.WithError(err).Error("failed to drain asset deletions")
+		return model.AssetDeletionResult{}, err
+	}
+
+	return result, nil
+}
+
+func (r *assetDeletionRepository) Stats(ctx context.Context) (model.AssetDeletionStats, error) {
+	var stats model.AssetDeletionStats
+
+	if err := r.db.
+		WithContext(ctx).
+		Model(&model.AssetDeletion{}).
+		Select(
+			"COUNT(*) FILTER (WHERE status = ?) AS pending, COUNT(*) FILTER (WHERE status = ?) AS dead, MIN(created_at) FILTER (WHERE status = ?) AS oldest_pending",
+			model.AssetDeletionStatusPending, model.AssetDeletionStatusDead, model.AssetDeletionStatusPending,
+		).
+		Scan(&stats).Error; err != nil {
+		logrus.WithError(err).Error("failed to count asset deletions")
+		return model.AssetDeletionStats{}, err
+	}
+
+	return stats, nil
+}
+
+// RetryDead moves dead deletions back to the queue with a fresh attempt count.
+func (r *assetDeletionRepository) RetryDead(ctx context.Context) (int64, error) {
+	result := r.db.
+		WithContext(ctx).
+		Model(&model.AssetDeletion{}).
+		Where("status = ?", model.AssetDeletionStatusDead).
+		Updates(map[string]interface{}{
+			"status":          model.AssetDeletionStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"updated_at":      time.Now(),
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("failed to retry dead asset deletions")
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// enqueueAssetDeletions queues publicIDs on tx so the delete commits or rolls
+// back together with the rows that referenced them.
+func enqueueAssetDeletions(tx *gorm.DB, publicIDs []string) error {
+	var deletions []model.AssetDeletion
+
+	for _, publicID := range publicIDs {
+		if publicID == "" {
+			continue
+		}
+
+		deletions = append(deletions, model.AssetDeletion{
+			ID:            ulid.Make().String(),
+			PublicID:      publicID,
+			Status:        model.AssetDeletionStatusPending,
+			NextAttemptAt: time.Now(),
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		})
+	}
+
+	if len(deletions) == 0 {
+		return nil
+	}
+
+	return tx.Create(&deletions).Error
+}
+
+func assetDeletionBackoff(attempts int) time.Duration {
+	backoff := assetDeletionBaseBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > assetDeletionMaxBackoff {
+		return assetDeletionMaxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const reconcileDeleteBatch = 100
+
+type assetReconciler struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+	prefix       string
+	gracePeriod  time.Duration
+}
+
+// NewAssetReconciler compares assets under prefix with photo rows. Assets
+// younger than gracePeriod are ignored since they may belong to an upload
+// that has not saved its row yet.
+func NewAssetReconciler(db *gorm.DB, uploaderRepo model.UploaderRepository, prefix string, gracePeriod time.Duration) model.AssetReconciler {
+	return &assetReconciler{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+		prefix:       prefix,
+		gracePeriod:  gracePeriod,
+	}
+}
+
+func (r *assetReconciler) Reconcile(ctx context.Context, dryRun bool) (model.ReconcileReport, error) {
+	logger := logrus.WithField("prefix", r.prefix)
+
+	report := model.ReconcileReport{
+		DryRun:        dryRun,
+		Prefix:        r.prefix,
+		OrphanAssets:  []string{},
+		MissingAssets: []string{},
+	}
+
+	assets, err := r.uploaderRepo.List(ctx, r.prefix)
+	if err != nil {
+		logger.WithError(err).Error("failed to list assets")
+		return report, err
+	}
+
+	var photoIDs, variantIDs []string
+
+	if err := r.db.WithContext(ctx).
+		Model(&model.Photo{}).
+		Where("public_id LIKE ?", r.prefix+"%").
+		Pluck("public_id", &photoIDs).Error; err != nil {
+		logger.WithError(err).Error("failed to find photo public ids")
+		return report, err
+	}
+
+	if err := r.db.WithContext(ctx).
+		Model(&model.PhotoVariant{}).
+		Where("public_id LIKE ?", r.prefix+"%").
+		Pluck("public_id", &variantIDs).Error; err != nil {
+		logger.WithError(err).Error("failed to find variant public ids")
+		return report, err
+	}
+
+	var posterIDs []string
+
+	if err := r.db.WithContext(ctx).
+		Model(&model.Photo{}).
+		Where("poster_public_id LIKE ?", r.prefix+"%").
+		Pluck("poster_public_id", &posterIDs).Error; err != nil {
+		logger.WithError(err).Error("failed to find poster public ids")
+		return report, err
+	}
+
+	var logoIDs []string
+
+	if err := r.db.WithContext(ctx).
+		Model(&model.Portfolio{}).
+		Where("watermark_settings->>'logo_public_id' LIKE ?", r.prefix+"%").
+		Pluck("watermark_settings->>'logo_public_id'", &logoIDs).Error; err != nil {
+		logger.WithError(err).Error("failed to find watermark logo public ids")
+		return report, err
+	}
+
+	report.Assets = len(assets)
+	report.Rows = len(photoIDs) + len(variantIDs) + len(posterIDs)
+
+	stored := map[string]bool{}
+	for _, a := range assets {
+		stored[a.PublicID] = true
+	}
+
+	// Logos are referenced but have no row to delete, so they never count
+	// as missing.
+	referenced := map[string]bool{}
+	for _, id := range logoIDs {
+		referenced[id] = true
+	}
+
+	for _, id := range append(append(photoIDs, variantIDs...), posterIDs...) {
+		referenced[id] = true
+
+		if !stored[id] {
+			report.MissingAssets = append(report.MissingAssets, id)
+		}
+	}
+
+	cutoff := time.Now().Add(-r.gracePeriod)
+
+	for _, a := range assets {
+		if !referenced[a.PublicID] && a.CreatedAt.Before(cutoff) {
+			report.OrphanAssets = append(report.OrphanAssets, a.PublicID)
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for start := 0; start < len(report.OrphanAssets); start += reconcileDeleteBatch {
+		end := min(start+reconcileDeleteBatch, len(report.OrphanAssets))
+
+		if err := r.uploaderRepo.DeleteByPublicIDs(ctx, report.OrphanAssets[start:end]); err != nil {
+			logger.WithError(err).Error("failed to delete orphan assets")
+			return report, err
+		}
+
+		report.DeletedAssets = end
+	}
+
+	// An empty listing more likely means misconfigured storage than a bucket
+	// that lost every file, so rows are kept.
+	if len(assets) == 0 || len(report.MissingAssets) == 0 {
+		return report, nil
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Model(&model.Photo{}).
+			Distinct().
+			Where("user_id IS NOT NULL").
+			Where("public_id IN ? OR id IN (SELECT photo_id FROM photo_variants WHERE public_id IN ?)", report.MissingAssets, report.MissingAssets).
+			Pluck("user_id", &report.UserIDs).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("public_id IN ?", report.MissingAssets).Delete(&model.PhotoVariant{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		report.DeletedRows += result.RowsAffected
+
+		if err := releaseUsage(tx, "public_id IN ?", report.MissingAssets); err != nil {
+			return err
+		}
+
+		result = tx.Where("public_id IN ?", report.MissingAssets).Delete(&model.Photo{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		report.DeletedRows += result.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to delete rows without assets")
+		return report, err
+	}
+
+	return report, nil
+}
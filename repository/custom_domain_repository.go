@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type customDomainRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomDomainRepository(db *gorm.DB) model.CustomDomainRepository {
+	return &customDomainRepository{db}
+}
+
+// Create registers a hostname unless the user already has it or another
+// account has verified it.
+func (r *customDomainRepository) Create(ctx context.Context, domain model.CustomDomain) error {
+	logger := logrus.WithField("hostname", domain.Hostname)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var taken int64
+
+		if err := tx.Model(&model.CustomDomain{}).
+			Where("hostname = ? AND (user_id = ? OR status = ?)", domain.Hostname, domain.UserID, model.DomainStatusVerified).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+
+		if taken > 0 {
+			return model.ErrDomainTaken
+		}
+
+		return tx.Create(&domain).Error
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to create custom domain")
+		return err
+	}
+
+	return nil
+}
+
+func (r *customDomainRepository) FindByID(ctx context.Context, id string) (model.CustomDomain, error) {
+	var domain model.CustomDomain
+
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&domain).Error; err != nil {
+		logrus.WithField("id", id).WithError(err).Error("failed to find custom domain")
+		return model.CustomDomain{}, err
+	}
+
+	return domain, nil
+}
+
+func (r *customDomainRepository) FindByUserID(ctx context.Context, userID string) ([]model.CustomDomain, error) {
+	domains := []model.CustomDomain{}
+
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&domains).Error; err != nil {
+		logrus.WithField("user_id", userID).WithError(err).Error("failed to find custom domains")
+		return nil, err
+	}
+
+	return domains, nil
+}
+
+func (r *customDomainRepository) Delete(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.CustomDomain{}).Error; err != nil {
+		logrus.WithField("id", id).WithError(err).Error("failed to delete custom domain")
+		return err
+	}
+
+	return nil
+}
+
+func (r *customDomainRepository) FindUsernameByHost(ctx context.Context, host string) (string, error) {
+	var username string
+
+	err := r.db.WithContext(ctx).
+		Model(&model.CustomDomain{}).
+		Select("users.username").
+		Joins("JOIN users ON users.id = custom_domains.user_id").
+		Where("custom_domains.hostname = ? AND custom_domains.status = ?", host, model.DomainStatusVerified).
+		Where("users.username <> '' AND users.deleted_at IS NULL").
+		Where("custom_domains.user_id IN (?)", entitledUsers(r.db)).
+		Limit(1).
+		Scan(&username).Error
+	if err != nil {
+		logrus.WithField("host", host).WithError(err).Error("failed to find custom domain")
+		return "", err
+	}
+
+	if username == "" {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return username, nil
+}
+
+// entitledUsers selects the users whose active plan includes custom domains.
+func entitledUsers(db *gorm.DB) *gorm.DB {
+	return db.Table("memberships").
+		Select("memberships.user_id").
+		Joins("JOIN membership_plans ON membership_plans.id = memberships.membership_plan_id").
+		Where("memberships.status = ? AND membership_plans.custom_domain = ?", model.MembershipStatusActive, true)
+}
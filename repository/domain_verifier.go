@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type domainVerifier struct {
+	db       *gorm.DB
+	resolver model.TXTResolver
+}
+
+func NewDomainVerifier(db *gorm.DB, resolver model.TXTResolver) model.DomainVerifier {
+	return &domainVerifier{
+		db:       db,
+		resolver: resolver,
+	}
+}
+
+// Verify looks up the domain's TXT record and records the outcome. Pending
+// domains stay pending on a miss until the verification window closes.
+func (v *domainVerifier) Verify(ctx context.Context, domain model.CustomDomain) (model.CustomDomain, error) {
+	logger := logrus.WithField("hostname", domain.Hostname)
+
+	now := time.Now()
+	checkErr := domain.CheckOwnership(ctx, v.resolver)
+
+	err := v.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if checkErr == nil {
+			var taken int64
+
+			if err := tx.Model(&model.CustomDomain{}).
+				Where("hostname = ? AND id <> ? AND status = ?", domain.Hostname, domain.ID, model.DomainStatusVerified).
+				Count(&taken).Error; err != nil {
+				return err
+			}
+
+			if taken > 0 {
+				checkErr = model.ErrDomainTaken
+			}
+		}
+
+		switch {
+		case checkErr == nil:
+			domain.Status = model.DomainStatusVerified
+			domain.FailureReason = ""
+			domain.VerifiedAt.Time, domain.VerifiedAt.Valid = now, true
+		case domain.Status == model.DomainStatusPending && now.Before(domain.CreatedAt.Add(model.DomainVerificationWindow)) && !errors.Is(checkErr, model.ErrDomainTaken):
+			domain.FailureReason = checkErr.Error()
+		default:
+			domain.Status = model.DomainStatusFailed
+			domain.FailureReason = checkErr.Error()
+		}
+
+		domain.CheckedAt.Time, domain.CheckedAt.Valid = now, true
+		domain.UpdatedAt = now
+
+		return tx.Model(&model.CustomDomain{}).
+			Where("id = ?", domain.ID).
+			Updates(map[string]interface{}{
+				"status":         domain.Status,
+				"failure_reason": domain.FailureReason,
+				"checked_at":     domain.CheckedAt,
+				"verified_at":    domain.VerifiedAt,
+				"updated_at":     domain.UpdatedAt,
+			}).Error
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to record domain verification")
+		return model.CustomDomain{}, err
+	}
+
+	return domain, nil
+}
+
+// VerifyPending checks the pending domains checked longest ago and returns
+// how many got verified.
+func (v *domainVerifier) VerifyPending(ctx context.Context, limit int) (int, error) {
+	var domains []model.CustomDomain
+
+	if err := v.db.WithContext(ctx).
+		Where("status = ?", model.DomainStatusPending).
+		Order("checked_at ASC NULLS FIRST").
+		Limit(limit).
+		Find(&domains).Error; err != nil {
+		logrus.WithError(err).Error("failed to find pending domains")
+		return 0, err
+	}
+
+	verified := 0
+
+	for _, domain := range domains {
+		domain, err := v.Verify(ctx, domain)
+		if err != nil {
+			return verified, err
+		}
+
+		if domain.Status == model.DomainStatusVerified {
+			verified++
+		}
+	}
+
+	return verified, nil
+}
+
+func (v *domainVerifier) DeactivateIneligible(ctx context.Context) (int, error) {
+	result := v.db.WithContext(ctx).
+		Model(&model.CustomDomain{}).
+		Where("status IN ?", []string{model.DomainStatusPending, model.DomainStatusVerified}).
+		Where("user_id NOT IN (?)", entitledUsers(v.db)).
+		Updates(map[string]interface{}{
+			"status":         model.DomainStatusDeactivated,
+			"failure_reason": model.ErrCustomDomainNotAllowed.Error(),
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("failed to deactivate custom domains")
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+}
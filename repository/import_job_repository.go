@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type importJobRepository struct {
+	db *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) model.ImportJobRepository {
+	return &importJobRepository{db}
+}
+
+func (r *importJobRepository) Create(ctx context.Context, job model.ImportJob) error {
+	logger := logrus.WithField("job", utils.Dump(job))
+
+	if err := r.db.
+		WithContext(ctx).
+		Create(&job).Error; err != nil {
+		logger.WithError(err).Error("failed to create import job")
+		return err
+	}
+
+	return nil
+}
+
+func (r *importJobRepository) FindByID(ctx context.Context, id string) (model.ImportJob, error) {
+	var job model.ImportJob
+
+	if err := r.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		First(&job).Error; err != nil {
+		logrus.WithField("id", id).WithError(err).Error("failed to find import job")
+		return model.ImportJob{}, err
+	}
+
+	return job, nil
+}
+
+func (r *importJobRepository) Update(ctx context.Context, job model.ImportJob) error {
+	logger := logrus.WithField("job", utils.Dump(job))
+
+	job.UpdatedAt = time.Now()
+
+	if err := r.db.
+		WithContext(ctx).
+		Save(&job).Error; err != nil {
+		logger.WithError(err).Error("failed to update import job")
+		return err
+	}
+
+	return nil
+}
+
+func (r *importJobRepository) FailUnfinished(ctx context.Context, reason string) (int64, error) {
+	result := r.db.
+		WithContext(ctx).
+		Model(&model.ImportJob{}).
+		Where("status IN ?", []string{model.ImportJobStatusPending, model.ImportJobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":     model.ImportJobStatusFailed,
+			"error":      reason,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("failed to fail unfinished import jobs")
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
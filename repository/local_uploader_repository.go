@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/video"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var extensionByContentType = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/gif":       ".gif",
+	"image/avif":      ".avif",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/quicktime": ".mov",
+}
+
+// detectContentType sniffs head, recognising QuickTime clips that
+// http.DetectContentType does not.
+func detectContentType(head []byte) string {
+	if video.Sniff(head) == video.FormatMOV {
+		return "video/quicktime"
+	}
+
+	return http.DetectContentType(head)
+}
+
+// sniffContentType detects the content type of file without consuming it,
+// returning a reader that still yields the whole file.
+func sniffContentType(file io.Reader) (io.Reader, string, error) {
+	reader := bufio.NewReader(file)
+
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+
+	return reader, detectContentType(head), nil
+}
+
+type localUploaderRepository struct {
+	photoStore
+	dir        string
+	privateDir string
+	baseURL    string
+	assetURL   string
+}
+
+// NewLocalUploaderRepository creates an uploader that stores files under dir
+// and serves them from baseURL. Private files are kept under privateDir,
+// which must not be served, and delivered through the asset route at
+// assetURL.
+func NewLocalUploaderRepository(dir, privateDir, baseURL, assetURL string, db *gorm.DB) model.UploaderRepository {
+	return &localUploaderRepository{
+		photoStore: photoStore{db: db},
+		dir:        dir,
+		privateDir: privateDir,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		assetURL:   strings.TrimSuffix(assetURL, "/"),
+	}
+}
+
+// Upload writes a file under the storage directory.
+func (u *localUploaderRepository) Upload(ctx context.Context, file io.Reader, folder string) (string, string, error) {
+	reader, contentType, err := sniffContentType(file)
+	if err != nil {
+		logrus.WithField("folder", folder).WithError(err).Error("failed to read file")
+		return "", "", err
+	}
+
+	publicID, err := u.write(u.dir, reader, folder, contentType)
+	if err != nil {
+		return "", "", err
+	}
+
+	return u.baseURL + "/" + publicID, publicID, nil
+}
+
+// UploadPrivate writes a file under the private directory.
+func (u *localUploaderRepository) UploadPrivate(ctx context.Context, file io.Reader, folder string) (string, string, error) {
+	reader, contentType, err := sniffContentType(file)
+	if err != nil {
+		logrus.WithField("folder", folder).WithError(err).Error("failed to read file")
+		return "", "", err
+	}
+
+	publicID, err := u.write(u.privateDir, reader, folder, contentType)
+	if err != nil {
+		return "", "", err
+	}
+
+	return u.assetURL + "/" + publicID, publicID, nil
+}
+
+// UploadVariant writes a rendered variant named after its format, which
+// sniffing does not recognise for AVIF.
+func (u *localUploaderRepository) UploadVariant(ctx context.Context, file io.Reader, folder, format string) (string, string, error) {
+	publicID, err := u.write(u.dir, file, folder, "image/"+format)
+	if err != nil {
+		return "", "", err
+	}
+
+	return u.baseURL + "/" + publicID, publicID, nil
+}
+
+// write stores file under root and returns its public ID.
+func (u *localUploaderRepository) write(root string, file io.Reader, folder, contentType string) (string, error) {
+	logger := logrus.WithField("folder", folder)
+
+	publicID := path.Join(strings.Trim(folder, "/"), ulid.Make().String()) + extensionByContentType[contentType]
+
+	target, err := resolvePath(root, publicID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		logger.WithError(err).Error("failed to create folder")
+		return "", err
+	}
+
+	dst, err := os.Create(target)
+	if err != nil {
+		logger.WithError(err).Error("failed to create file")
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		logger.WithError(err).Error("failed to write file")
+		os.Remove(target)
+		return "", err
+	}
+
+	return publicID, nil
+}
+
+// UploadVideo stores a clip like any other object.
+func (u *localUploaderRepository) UploadVideo(ctx context.Context, file io.Reader, folder string) (string, string, error) {
+	return u.Upload(ctx, file, folder)
+}
+
+// DeleteByPublicIDs deletes public and private files by public IDs. Missing
+// files are ignored.
+func (u *localUploaderRepository) DeleteByPublicIDs(ctx context.Context, publicIDs []string) error {
+	for _, publicID := range publicIDs {
+		for _, root := range u.roots() {
+			target, err := resolvePath(root, publicID)
+			if err != nil {
+				return err
+			}
+
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				logrus.WithField("public_id", publicID).WithError(err).Error("failed to delete file")
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteByPrefix deletes every file whose public ID starts with prefix.
+func (u *localUploaderRepository) DeleteByPrefix(ctx context.Context, prefix string) error {
+	assets, err := u.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	var publicIDs []string
+	for _, a := range assets {
+		publicIDs = append(publicIDs, a.PublicID)
+	}
+
+	return u.DeleteByPublicIDs(ctx, publicIDs)
+}
+
+// URL returns the static route URL of a file, or the asset route URL of a
+// private one.
+func (u *localUploaderRepository) URL(ctx context.Context, publicID string) (string, error) {
+	target, err := u.locate(publicID)
+	if err != nil {
+		return "", err
+	}
+
+	return u.srcURL(target, publicID), nil
+}
+
+// SignUpload is not supported, files can only reach local storage through
+// this server.
+func (u *localUploaderRepository) SignUpload(ctx context.Context, folder string, expiry time.Duration) (model.SignedUpload, error) {
+	return model.SignedUpload{}, model.ErrDirectUploadUnsupported
+}
+
+// FindAsset stats a stored file.
+func (u *localUploaderRepository) FindAsset(ctx context.Context, publicID string) (model.Asset, error) {
+	target, err := u.locate(publicID)
+	if err != nil {
+		return model.Asset{}, err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return model.Asset{}, model.ErrAssetNotFound
+		}
+
+		return model.Asset{}, err
+	}
+
+	return model.Asset{
+		PublicID:  publicID,
+		URL:       u.srcURL(target, publicID),
+		Bytes:     info.Size(),
+		CreatedAt: info.ModTime(),
+	}, nil
+}
+
+// List walks the public and private directories for files whose public ID
+// starts with prefix.
+func (u *localUploaderRepository) List(ctx context.Context, prefix string) ([]model.Asset, error) {
+	var assets []model.Asset
+
+	for _, root := range u.roots() {
+		found, err := u.list(root, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		assets = append(assets, found...)
+	}
+
+	return assets, nil
+}
+
+func (u *localUploaderRepository) list(root, prefix string) ([]model.Asset, error) {
+	var assets []model.Asset
+
+	err := filepath.WalkDir(root, func(p string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		publicID := filepath.ToSlash(rel)
+		if !strings.HasPrefix(publicID, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		assets = append(assets, model.Asset{
+			PublicID:  publicID,
+			URL:       u.srcURL(p, publicID),
+			Bytes:     info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		logrus.WithField("prefix", prefix).WithError(err).Error("failed to list files")
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+// Open opens a stored file.
+func (u *localUploaderRepository) Open(ctx context.Context, publicID string) (io.ReadCloser, error) {
+	target, err := u.locate(publicID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, model.ErrAssetNotFound
+		}
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// roots are the directories files are stored under, public first.
+func (u *localUploaderRepository) roots() []string {
+	return []string{u.dir, u.privateDir}
+}
+
+// locate maps a public ID to the path of its file, in the private directory
+// when it is stored there and otherwise in the public one.
+func (u *localUploaderRepository) locate(publicID string) (string, error) {
+	private, err := resolvePath(u.privateDir, publicID)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(private); err == nil {
+		return private, nil
+	}
+
+	return resolvePath(u.dir, publicID)
+}
+
+// srcURL returns the delivery URL of the file stored at target.
+func (u *localUploaderRepository) srcURL(target, publicID string) string {
+	if rel, err := filepath.Rel(u.privateDir, target); err == nil && !strings.HasPrefix(rel, "..") {
+		return u.assetURL + "/" + publicID
+	}
+
+	return u.baseURL + "/" + publicID
+}
+
+// resolvePath maps a public ID to a path inside root.
+func resolvePath(root, publicID string) (string, error) {
+	cleaned := path.Clean("/" + publicID)
+	if cleaned == "/" || cleaned != "/"+publicID {
+		return "", model.ErrInvalidPublicID
+	}
+
+	return filepath.Join(root, filepath.FromSlash(cleaned)), nil
+}
@@ -70,6 +70,23 @@ func (r *membershipRepository) FindByID(ctx context.Context, id string) (model.M
 	return membership, nil
 }
 
+func (r *membershipRepository) FindActiveByUserID(ctx context.Context, userID string) (model.Membership, error) {
+	logger := logrus.WithField("user_id", userID)
+
+	var membership model.Membership
+
+	if err := r.db.
+		WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, model.MembershipStatusActive).
+		Order("start_date DESC").
+		First(&membership).Error; err != nil {
+		logger.WithError(err).Error("failed to find active membership")
+		return model.Membership{}, err
+	}
+
+	return membership, nil
+}
+
 func (r *membershipRepository) Create(ctx context.Context, input model.Membership) error {
 	logger := logrus.WithField("input", utils.Dump(input))
 
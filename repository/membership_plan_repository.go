@@ -78,7 +78,7 @@ func (r *membershipPlanRepository) Create(ctx context.Context, input model.Membe
 func (r *membershipPlanRepository) Update(ctx context.Context, id string, input model.MembershipPlan) error {
 	logger := logrus.WithField("id", id).WithField("input", utils.Dump(input))
 
-	var toUpdate map[string]interface{}
+	toUpdate := map[string]interface{}{}
 
 	if input.Name != "" {
 		toUpdate["name"] = input.Name
@@ -104,10 +104,38 @@ func (r *membershipPlanRepository) Update(ctx context.Context, id string, input
 		toUpdate["max_folders"] = input.MaxFolders
 	}
 
+	if input.MaxUploadBytes != 0 {
+		toUpdate["max_upload_bytes"] = input.MaxUploadBytes
+	}
+
+	if input.MaxVideoBytes != 0 {
+		toUpdate["max_video_bytes"] = input.MaxVideoBytes
+	}
+
+	if input.MaxVideoSeconds != 0 {
+		toUpdate["max_video_seconds"] = input.MaxVideoSeconds
+	}
+
+	if input.MaxImageDimension != 0 {
+		toUpdate["max_image_dimension"] = input.MaxImageDimension
+	}
+
+	if input.MaxStorageBytes != 0 {
+		toUpdate["max_storage_bytes"] = input.MaxStorageBytes
+	}
+
+	if input.MaxPhotos != 0 {
+		toUpdate["max_photos"] = input.MaxPhotos
+	}
+
 	if input.CustomDomain {
 		toUpdate["custom_domain"] = input.CustomDomain
 	}
 
+	if input.PremiumThemes {
+		toUpdate["premium_themes"] = input.PremiumThemes
+	}
+
 	if input.AdvancedAnalytics {
 		toUpdate["advanced_analytics"] = input.AdvancedAnalytics
 	}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type ownershipRepository struct {
+	db *gorm.DB
+}
+
+func NewOwnershipRepository(db *gorm.DB) model.OwnershipRepository {
+	return &ownershipRepository{db}
+}
+
+func (r *ownershipRepository) FindOwners(ctx context.Context, kind string, ids []string) (map[string]string, error) {
+	logger := logrus.WithField("kind", kind)
+
+	owners := map[string]string{}
+
+	if len(ids) == 0 {
+		return owners, nil
+	}
+
+	query := r.db.WithContext(ctx)
+
+	switch kind {
+	case model.ResourcePortfolio:
+		query = query.Table("portfolios").Select("portfolios.id AS id, portfolios.user_id AS user_id").Where("portfolios.id IN ?", ids)
+	case model.ResourceProfile:
+		query = query.Table("profiles").
+			Select("profiles.id AS id, portfolios.user_id AS user_id").
+			Joins("JOIN portfolios ON portfolios.id = profiles.portfolio_id").
+			Where("profiles.id IN ?", ids)
+	case model.ResourceFolder:
+		query = query.Table("folders").
+			Select("folders.id AS id, portfolios.user_id AS user_id").
+			Joins("JOIN portfolios ON portfolios.id = folders.portfolio_id").
+			Where("folders.id IN ?", ids)
+	case model.ResourcePhoto:
+		query = photoOwners(query, "photos.id").Where("photos.id IN ?", ids)
+	case model.ResourceAsset:
+		query = photoOwners(query, "photos.public_id").Where("photos.public_id IN ?", ids)
+	case model.ResourceDomain:
+		query = query.Table("custom_domains").Select("custom_domains.id AS id, custom_domains.user_id AS user_id").Where("custom_domains.id IN ?", ids)
+	default:
+		return nil, fmt.Errorf("unknown resource kind: %s", kind)
+	}
+
+	var rows []struct {
+		ID     string
+		UserID string
+	}
+
+	if err := query.Scan(&rows).Error; err != nil {
+		logger.WithError(err).Error("failed to find resource owners")
+		return nil, err
+	}
+
+	for _, row := range rows {
+		owners[row.ID] = row.UserID
+	}
+
+	return owners, nil
+}
+
+// photoOwners falls back to the folder's portfolio for photos saved before
+// photos.user_id was filled in.
+func photoOwners(query *gorm.DB, idColumn string) *gorm.DB {
+	return query.Table("photos").
+		Select(idColumn + " AS id, COALESCE(photos.user_id, portfolios.user_id, '') AS user_id").
+		Joins("LEFT JOIN folders ON folders.id = photos.folder_id").
+		Joins("LEFT JOIN portfolios ON portfolios.id = folders.portfolio_id")
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"io"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type photoHashRepository struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+}
+
+func NewPhotoHashRepository(db *gorm.DB, uploaderRepo model.UploaderRepository) model.PhotoHashRepository {
+	return &photoHashRepository{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+	}
+}
+
+// Backfill computes the perceptual hash of images without one from their
+// stored originals. Photos that cannot be read are skipped and logged. It
+// returns the number of photos updated.
+func (r *photoHashRepository) Backfill(ctx context.Context) (int, error) {
+	var photos []model.Photo
+
+	updated := 0
+
+	err := r.db.
+		WithContext(ctx).
+		Where("phash = '' AND media_type = ?", model.MediaTypeImage).
+		FindInBatches(&photos, 100, func(tx *gorm.DB, batch int) error {
+			for _, photo := range photos {
+				logger := logrus.WithField("photo_id", photo.ID)
+
+				hash, err := r.hash(ctx, photo.PublicID)
+				if err != nil {
+					logger.WithError(err).Warn("failed to compute photo hash")
+					continue
+				}
+
+				if err := r.db.
+					WithContext(ctx).
+					Model(&model.Photo{}).
+					Where("id = ?", photo.ID).
+					Update("phash", hash).Error; err != nil {
+					return err
+				}
+
+				updated++
+			}
+
+			return nil
+		}).Error
+	if err != nil {
+		logrus.WithError(err).Error("failed to backfill photo hashes")
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+func (r *photoHashRepository) hash(ctx context.Context, publicID string) (string, error) {
+	file, err := r.uploaderRepo.Open(ctx, publicID)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	return imaging.DHash(buf)
+}
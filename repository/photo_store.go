@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// photoStore holds the photo persistence shared by every uploader backend.
+type photoStore struct {
+	db *gorm.DB
+}
+
+// SavePhoto saves a photo to the database and charges its size to the
+// owner's storage usage.
+func (s *photoStore) SavePhoto(ctx context.Context, photo model.Photo) error {
+	logger := logrus.WithField("photo", utils.Dump(photo))
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return savePhoto(tx, photo)
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to save photo")
+		return err
+	}
+
+	return nil
+}
+
+// AppendPhoto saves a photo after the last photo of its folder. The folder
+// row is locked so concurrent appends get distinct sort indexes.
+func (s *photoStore) AppendPhoto(ctx context.Context, photo model.Photo) error {
+	logger := logrus.WithField("photo", utils.Dump(photo))
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", photo.FolderID).
+			First(&model.Folder{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.
+			Model(&model.Photo{}).
+			Select("COALESCE(MAX(sort_index) + 1, 0)").
+			Where("folder_id = ?", photo.FolderID).
+			Scan(&photo.SortIndex).Error; err != nil {
+			return err
+		}
+
+		return savePhoto(tx, photo)
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to append photo")
+		return err
+	}
+
+	return nil
+}
+
+// savePhoto saves photo within tx and charges the size difference to the
+// owner's storage usage.
+func savePhoto(tx *gorm.DB, photo model.Photo) error {
+	var existing model.Photo
+
+	result := tx.Select("bytes").Where("id = ?", photo.ID).Limit(1).Find(&existing)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	photos := 1
+	if result.RowsAffected > 0 {
+		photos = 0
+	}
+
+	if err := tx.Save(&photo).Error; err != nil {
+		return err
+	}
+
+	return addUsage(tx, photo.UserID, photo.Bytes-existing.Bytes, photos)
+}
+
+// replacedPhotoColumns are the columns describing a photo's file. The rest
+// of the row is its identity and placement, which a replacement keeps.
+var replacedPhotoColumns = []string{
+	"src", "original_src", "public_id", "media_type", "width", "height", "duration_ms",
+	"poster_src", "poster_public_id", "metadata", "phash", "blurhash", "dominant_color",
+	"average_color", "bytes", "render_requested_at", "render_attempts",
+}
+
+// ReplacePhoto swaps the file of an existing photo for the one described by
+// photo. The old asset, poster and variants are queued for deletion and the
+// owner is charged the size difference.
+func (s *photoStore) ReplacePhoto(ctx context.Context, photo model.Photo) error {
+	logger := logrus.WithField("photo", utils.Dump(photo))
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.Photo
+
+		if err := tx.Preload("Variants").Where("id = ?", photo.ID).First(&existing).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("photo_id = ?", photo.ID).Delete(&model.PhotoVariant{}).Error; err != nil {
+			return err
+		}
+
+		if len(photo.Variants) > 0 {
+			if err := tx.Create(&photo.Variants).Error; err != nil {
+				return err
+			}
+		}
+
+		file := photo
+		file.Variants = nil
+
+		if err := tx.Model(&model.Photo{}).
+			Where("id = ?", photo.ID).
+			Select(replacedPhotoColumns).
+			Updates(&file).Error; err != nil {
+			return err
+		}
+
+		stale := append(variantPublicIDs(existing.Variants), existing.PublicID, existing.PosterPublicID)
+
+		if err := enqueueAssetDeletions(tx, stale); err != nil {
+			return err
+		}
+
+		return addUsage(tx, existing.UserID, photo.Bytes-existing.Bytes, 0)
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to replace photo")
+		return err
+	}
+
+	return nil
+}
+
+// FindByID finds a photo with its variants.
+func (s *photoStore) FindByID(ctx context.Context, id string) (model.Photo, error) {
+	logger := logrus.WithField("id", id)
+
+	var photo model.Photo
+
+	if err := s.db.WithContext(ctx).Preload("Variants").Where("id = ?", id).First(&photo).Error; err != nil {
+		logger.WithError(err).Error("failed to find photo")
+		return model.Photo{}, err
+	}
+
+	return photo, nil
+}
+
+// FindByPublicIDs finds photos with their variants by public IDs.
+func (s *photoStore) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]model.Photo, error) {
+	logger := logrus.WithField("public_ids", publicIDs)
+
+	var photos []model.Photo
+
+	err := s.db.WithContext(ctx).Preload("Variants").Where("public_id IN (?)", publicIDs).Find(&photos).Error
+	if err != nil {
+		logger.WithError(err).Error("failed to find photos")
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// IsPublishedAsset looks publicID up among the delivered assets of photos in
+// published portfolios. The original of a watermarked photo is not one.
+func (s *photoStore) IsPublishedAsset(ctx context.Context, publicID string) (bool, error) {
+	var count int64
+
+	err := s.db.
+		WithContext(ctx).
+		Model(&model.Photo{}).
+		Joins("JOIN folders ON folders.id = photos.folder_id").
+		Joins("JOIN portfolios ON portfolios.id = folders.portfolio_id").
+		Where("portfolios.published").
+		Where(s.db.
+			Where("photos.public_id = ? AND (photos.original_src = '' OR photos.src = photos.original_src)", publicID).
+			Or("photos.poster_public_id = ?", publicID).
+			Or("EXISTS (SELECT 1 FROM photo_variants WHERE photo_variants.photo_id = photos.id AND photo_variants.public_id = ?)", publicID)).
+		Count(&count).Error
+	if err != nil {
+		logrus.WithField("public_id", publicID).WithError(err).Error("failed to find published asset")
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// FindHashedByUserID finds the user's photos that have a perceptual hash.
+func (s *photoStore) FindHashedByUserID(ctx context.Context, userID string) ([]model.Photo, error) {
+	logger := logrus.WithField("user_id", userID)
+
+	var photos []model.Photo
+
+	err := s.db.
+		WithContext(ctx).
+		Where("user_id = ? AND phash <> ''", userID).
+		Order("created_at ASC").
+		Find(&photos).Error
+	if err != nil {
+		logger.WithError(err).Error("failed to find hashed photos")
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// FindByFolderID finds the photos of a folder with their variants, in
+// display order.
+func (s *photoStore) FindByFolderID(ctx context.Context, folderID string) ([]model.Photo, error) {
+	logger := logrus.WithField("folder_id", folderID)
+
+	var photos []model.Photo
+
+	err := s.db.
+		WithContext(ctx).
+		Preload("Variants").
+		Where("folder_id = ?", folderID).
+		Order("sort_index ASC, created_at ASC").
+		Find(&photos).Error
+	if err != nil {
+		logger.WithError(err).Error("failed to find folder photos")
+		return nil, err
+	}
+
+	return photos, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"io"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type placeholderRepository struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+}
+
+func NewPlaceholderRepository(db *gorm.DB, uploaderRepo model.UploaderRepository) model.PlaceholderRepository {
+	return &placeholderRepository{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+	}
+}
+
+// Backfill computes placeholders for photos without a BlurHash from their
+// stored originals. Photos that cannot be read are skipped and logged. It
+// returns the number of photos updated.
+func (r *placeholderRepository) Backfill(ctx context.Context) (int, error) {
+	var photos []model.Photo
+
+	updated := 0
+
+	err := r.db.
+		WithContext(ctx).
+		Where("blurhash = ''").
+		FindInBatches(&photos, 100, func(tx *gorm.DB, batch int) error {
+			for _, photo := range photos {
+				logger := logrus.WithField("photo_id", photo.ID)
+
+				// Videos are summarised by their poster frame.
+				publicID := photo.PublicID
+				if photo.IsVideo() {
+					publicID = photo.PosterPublicID
+				}
+
+				placeholder, err := r.placeholder(ctx, publicID)
+				if err != nil {
+					logger.WithError(err).Warn("failed to compute photo placeholder")
+					continue
+				}
+
+				if err := r.db.
+					WithContext(ctx).
+					Model(&model.Photo{}).
+					Where("id = ?", photo.ID).
+					Updates(map[string]interface{}{
+						"blurhash":       placeholder.BlurHash,
+						"dominant_color": placeholder.DominantColor,
+						"average_color":  placeholder.AverageColor,
+					}).Error; err != nil {
+					return err
+				}
+
+				updated++
+			}
+
+			return nil
+		}).Error
+	if err != nil {
+		logrus.WithError(err).Error("failed to backfill photo placeholders")
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+func (r *placeholderRepository) placeholder(ctx context.Context, publicID string) (imaging.Placeholder, error) {
+	file, err := r.uploaderRepo.Open(ctx, publicID)
+	if err != nil {
+		return imaging.Placeholder{}, err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(file)
+	if err != nil {
+		return imaging.Placeholder{}, err
+	}
+
+	return imaging.NewPlaceholder(buf)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/notblessy/ekspresi-core/model"
 	"github.com/notblessy/ekspresi-core/utils"
@@ -29,13 +30,37 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 
 	porto := input.GetPortfolio()
 
+	// Re-rendering every photo is expensive, so it is only requested when
+	// the stored watermark would render differently.
+	watermarkChanged := false
+
+	if porto.ID != "" && porto.WatermarkSettings != nil {
+		var stored model.Portfolio
+
+		if err := tx.Select("watermark_settings").Where("id = ?", porto.ID).First(&stored).Error; err != nil {
+			tx.Rollback()
+			logger.WithError(err).Error("failed to find watermark settings")
+			return err
+		}
+
+		watermarkChanged = !porto.WatermarkSettings.SameRender(stored.WatermarkSettings)
+	}
+
 	if porto.ID != "" {
-		var portfolioToUpdate map[string]interface{}
+		portfolioToUpdate := map[string]interface{}{}
 
 		if porto.Title != "" {
 			portfolioToUpdate["title"] = porto.Title
 		}
 
+		if porto.Theme != "" {
+			portfolioToUpdate["theme"] = porto.Theme
+		}
+
+		if porto.ThemeSettings != nil {
+			portfolioToUpdate["theme_settings"] = porto.ThemeSettings
+		}
+
 		if porto.Columns != 0 {
 			portfolioToUpdate["columns"] = porto.Columns
 		}
@@ -52,6 +77,18 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 			portfolioToUpdate["show_captions"] = porto.ShowCaptions
 		}
 
+		if porto.MetadataSettings != nil {
+			portfolioToUpdate["metadata_settings"] = porto.MetadataSettings
+		}
+
+		if porto.WatermarkSettings != nil {
+			portfolioToUpdate["watermark_settings"] = porto.WatermarkSettings
+		}
+
+		if porto.Published != nil {
+			portfolioToUpdate["published"] = *porto.Published
+		}
+
 		if err := tx.Model(&model.Portfolio{}).Where("id = ?", porto.ID).Updates(portfolioToUpdate).Error; err != nil {
 			tx.Rollback()
 			logger.WithError(err).Error("failed to update portfolio")
@@ -62,7 +99,7 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 	profile := input.GetProfiles()
 
 	if profile.ID != "" {
-		var profileToUpdate map[string]interface{}
+		profileToUpdate := map[string]interface{}{}
 
 		if profile.Name != "" {
 			profileToUpdate["name"] = profile.Name
@@ -84,6 +121,10 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 			profileToUpdate["instagram"] = profile.Instagram
 		}
 
+		if profile.ShowEmail != nil {
+			profileToUpdate["show_email"] = *profile.ShowEmail
+		}
+
 		if profile.Website != "" {
 			profileToUpdate["website"] = profile.Website
 		}
@@ -97,17 +138,22 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 	}
 
 	if len(input.DeletedPhotos) > 0 {
-		if err := tx.Where("public_id IN ?", input.DeletedPhotos).Delete(&model.Photo{}).Error; err != nil {
+		if err := deletePhotos(tx, "public_id IN ?", input.DeletedPhotos); err != nil {
 			tx.Rollback()
 			logger.WithError(err).Error("failed to delete photos")
 
 			return err
 		}
-
-		go p.uploaderRepo.DeleteByPublicIDs(ctx, input.DeletedPhotos)
 	}
 
 	if len(input.DeletedFolders) > 0 {
+		if err := deletePhotos(tx, "folder_id IN ?", input.DeletedFolders); err != nil {
+			tx.Rollback()
+			logger.WithError(err).Error("failed to delete folder photos")
+
+			return err
+		}
+
 		if err := tx.Where("id IN ?", input.DeletedFolders).Delete(&model.Folder{}).Error; err != nil {
 			tx.Rollback()
 			logger.WithError(err).Error("failed to delete folders")
@@ -153,6 +199,10 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 			existingFolder.CoverID = folder.CoverID
 		}
 
+		if folder.AllowDownload != nil {
+			existingFolder.AllowDownload = folder.AllowDownload
+		}
+
 		if err := tx.Save(&existingFolder).Error; err != nil {
 			tx.Rollback()
 			logger.WithError(err).Error("failed to save folder")
@@ -182,10 +232,147 @@ func (p *portfolioRepository) Patch(ctx context.Context, input model.PortfolioTy
 		}
 	}
 
+	// Photos are saved above from rows read outside tx, so the render request
+	// goes last to not be overwritten.
+	if watermarkChanged {
+		if err := tx.Model(&model.Photo{}).
+			Where("user_id = (?) AND media_type = ?", tx.Model(&model.Portfolio{}).Select("user_id").Where("id = ?", porto.ID), model.MediaTypeImage).
+			Updates(map[string]interface{}{
+				"render_requested_at": time.Now(),
+				"render_attempts":     0,
+			}).Error; err != nil {
+			tx.Rollback()
+			logger.WithError(err).Error("failed to request photo renders")
+			return err
+		}
+	}
+
 	tx.Commit()
 	return nil
 }
 
+func (p *portfolioRepository) FindByID(ctx context.Context, id string) (model.Portfolio, error) {
+	logger := logrus.WithField("id", id)
+
+	var portfolio model.Portfolio
+
+	if err := p.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		First(&portfolio).Error; err != nil {
+		logger.WithError(err).Error("failed to find portfolio")
+		return model.Portfolio{}, err
+	}
+
+	return portfolio, nil
+}
+
+func (p *portfolioRepository) FindByUserID(ctx context.Context, userID string) (model.Portfolio, error) {
+	logger := logrus.WithField("user_id", userID)
+
+	var portfolio model.Portfolio
+
+	if err := p.db.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&portfolio).Error; err != nil {
+		logger.WithError(err).Error("failed to find portfolio")
+		return model.Portfolio{}, err
+	}
+
+	return portfolio, nil
+}
+
+func (p *portfolioRepository) FindFolderByID(ctx context.Context, id string) (model.Folder, error) {
+	logger := logrus.WithField("id", id)
+
+	var folder model.Folder
+
+	if err := p.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		First(&folder).Error; err != nil {
+		logger.WithError(err).Error("failed to find folder")
+		return model.Folder{}, err
+	}
+
+	return folder, nil
+}
+
+// FindPublishedByUsername finds the published portfolio of a user with its
+// folders, photos in display order and variants by width.
+func (p *portfolioRepository) FindPublishedByUsername(ctx context.Context, username string) (model.PortfolioType, error) {
+	logger := logrus.WithField("username", username)
+
+	var portfolio model.PortfolioType
+
+	err := p.db.
+		WithContext(ctx).
+		Joins("JOIN users ON users.id = portfolios.user_id").
+		Where("LOWER(users.username) = LOWER(?) AND users.username <> '' AND users.deleted_at IS NULL", username).
+		Where("portfolios.published = ?", true).
+		Preload("Profiles").
+		Preload("Folders", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Folders.Photos", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_index ASC, created_at ASC")
+		}).
+		Preload("Folders.Photos.Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("width ASC")
+		}).
+		First(&portfolio).Error
+	if err != nil {
+		logger.WithError(err).Error("failed to find published portfolio")
+		return model.PortfolioType{}, err
+	}
+
+	return portfolio, nil
+}
+
+// deletePhotos deletes the photos matching query, releases their storage
+// usage and queues their files, variants and posters for deletion.
+func deletePhotos(tx *gorm.DB, query interface{}, args ...interface{}) error {
+	var publicIDs []string
+
+	if err := tx.Model(&model.Photo{}).
+		Where(query, args...).
+		Pluck("public_id", &publicIDs).Error; err != nil {
+		return err
+	}
+
+	if len(publicIDs) == 0 {
+		return nil
+	}
+
+	var variantPublicIDs []string
+
+	if err := tx.Model(&model.PhotoVariant{}).
+		Where("photo_id IN (?)", tx.Model(&model.Photo{}).Select("id").Where(query, args...)).
+		Pluck("public_id", &variantPublicIDs).Error; err != nil {
+		return err
+	}
+
+	var posterPublicIDs []string
+
+	if err := tx.Model(&model.Photo{}).
+		Where(query, args...).
+		Where("poster_public_id <> ''").
+		Pluck("poster_public_id", &posterPublicIDs).Error; err != nil {
+		return err
+	}
+
+	if err := releaseUsage(tx, query, args...); err != nil {
+		return err
+	}
+
+	if err := tx.Where(query, args...).Delete(&model.Photo{}).Error; err != nil {
+		return err
+	}
+
+	return enqueueAssetDeletions(tx, append(append(publicIDs, variantPublicIDs...), posterPublicIDs...))
+}
+
 func folderToDict(folders []model.Folder) map[string]model.Folder {
 	dict := make(map[string]model.Folder)
 
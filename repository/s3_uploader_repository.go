@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// S3UploaderConfig describes where assets are stored and how they are delivered.
+type S3UploaderConfig struct {
+	Bucket string
+	// PublicURL is the base URL of a publicly readable bucket or CDN. When
+	// empty, assets are delivered through presigned URLs.
+	PublicURL string
+	// AssetURL is the base URL of the asset redirect route, used as Photo.Src
+	// for private buckets so the stored URL never expires.
+	AssetURL string
+	// PrivateBucket holds files that are never delivered on their own, such
+	// as unwatermarked originals. It defaults to Bucket, which is only safe
+	// when Bucket is not public.
+	PrivateBucket string
+	PresignExpiry time.Duration
+}
+
+type s3UploaderRepository struct {
+	photoStore
+	client *minio.Client
+	config S3UploaderConfig
+}
+
+// NewS3UploaderRepository creates an uploader backed by an S3-compatible bucket.
+func NewS3UploaderRepository(client *minio.Client, config S3UploaderConfig, db *gorm.DB) model.UploaderRepository {
+	config.PublicURL = strings.TrimSuffix(config.PublicURL, "/")
+	config.AssetURL = strings.TrimSuffix(config.AssetURL, "/")
+
+	if config.PrivateBucket == "" {
+		config.PrivateBucket = config.Bucket
+	}
+
+	return &s3UploaderRepository{
+		photoStore: photoStore{db: db},
+		client:     client,
+		config:     config,
+	}
+}
+
+// Upload uploads a file to the bucket under the given folder.
+func (u *s3UploaderRepository) Upload(ctx context.Context, file io.Reader, folder string) (string, string, error) {
+	reader, contentType, err := sniffContentType(file)
+	if err != nil {
+		logrus.WithField("folder", folder).WithError(err).Error("failed to read file")
+		return "", "", err
+	}
+
+	key, err := u.put(ctx, u.config.Bucket, reader, folder, contentType)
+	if err != nil {
+		return "", "", err
+	}
+
+	return u.srcURL(key), key, nil
+}
+
+// UploadPrivate uploads a file to the private bucket under the given folder.
+func (u *s3UploaderRepository) UploadPrivate(ctx context.Context, file io.Reader, folder string) (string, string, error) {
+	reader, contentType, err := sniffContentType(file)
+	if err != nil {
+		logrus.WithField("folder", folder).WithError(err).Error("failed to read file")
+		return "", "", err
+	}
+
+	key, err := u.put(ctx, u.config.PrivateBucket, reader, folder, contentType)
+	if err != nil {
+		return "", "", err
+	}
+
+	return u.config.AssetURL + "/" + key, key, nil
+}
+
+// UploadVariant uploads a rendered variant with the content type of its
+// format, which sniffing does not recognise for AVIF.
+func (u *s3UploaderRepository) UploadVariant(ctx context.Context, file io.Reader, folder, format string) (string, string, error) {
+	key, err := u.put(ctx, u.config.Bucket, file, folder, "image/"+format)
+	if err != nil {
+		return "", "", err
+	}
+
+	return u.srcURL(key), key, nil
+}
+
+// put stores file in bucket and returns its key.
+func (u *s3UploaderRepository) put(ctx context.Context, bucket string, file io.Reader, folder, contentType string) (string, error) {
+	key := path.Join(strings.Trim(folder, "/"), ulid.Make().String()) + extensionByContentType[contentType]
+
+	_, err := u.client.PutObject(ctx, bucket, key, file, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		logrus.WithField("folder", folder).WithError(err).Error("failed to put object")
+		return "", err
+	}
+
+	return key, nil
+}
+
+// UploadVideo stores a clip like any other object.
+func (u *s3UploaderRepository) UploadVideo(ctx context.Context, file io.Reader, folder string) (string, string, error) {
+	return u.Upload(ctx, file, folder)
+}
+
+// DeleteByPublicIDs deletes objects by key in batched requests, from the
+// public and private buckets.
+func (u *s3UploaderRepository) DeleteByPublicIDs(ctx context.Context, publicIDs []string) error {
+	for _, bucket := range u.buckets() {
+		objects := make(chan minio.ObjectInfo, len(publicIDs))
+		for _, publicID := range publicIDs {
+			objects <- minio.ObjectInfo{Key: publicID}
+		}
+		close(objects)
+
+		if err := u.removeObjects(ctx, bucket, objects); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteByPrefix deletes every object whose key starts with prefix, from the
+// public and private buckets.
+func (u *s3UploaderRepository) DeleteByPrefix(ctx context.Context, prefix string) error {
+	for _, bucket := range u.buckets() {
+		if err := u.deleteByPrefix(ctx, bucket, prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *s3UploaderRepository) deleteByPrefix(ctx context.Context, bucket, prefix string) error {
+	var listErr error
+
+	objects := make(chan minio.ObjectInfo)
+
+	go func() {
+		defer close(objects)
+
+		for object := range u.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		}) {
+			if object.Err != nil {
+				if listErr == nil {
+					listErr = object.Err
+				}
+
+				continue
+			}
+
+			objects <- object
+		}
+	}()
+
+	if err := u.removeObjects(ctx, bucket, objects); err != nil {
+		return err
+	}
+
+	return listErr
+}
+
+// URL returns a delivery URL for the object, presigned for private buckets.
+func (u *s3UploaderRepository) URL(ctx context.Context, publicID string) (string, error) {
+	if u.config.PublicURL != "" {
+		return u.config.PublicURL + "/" + publicID, nil
+	}
+
+	presigned, err := u.client.PresignedGetObject(ctx, u.config.Bucket, publicID, u.config.PresignExpiry, nil)
+	if err != nil {
+		logrus.WithField("public_id", publicID).WithError(err).Error("failed to presign object")
+		return "", err
+	}
+
+	return presigned.String(), nil
+}
+
+// SignUpload presigns a PUT of a new object under path.
+func (u *s3UploaderRepository) SignUpload(ctx context.Context, folder string, expiry time.Duration) (model.SignedUpload, error) {
+	key := path.Join(strings.Trim(folder, "/"), ulid.Make().String())
+
+	presigned, err := u.client.PresignedPutObject(ctx, u.config.Bucket, key, expiry)
+	if err != nil {
+		logrus.WithField("key", key).WithError(err).Error("failed to presign upload")
+		return model.SignedUpload{}, err
+	}
+
+	return model.SignedUpload{
+		URL:       presigned.String(),
+		Method:    http.MethodPut,
+		PublicID:  key,
+		ExpiresAt: time.Now().Add(expiry),
+	}, nil
+}
+
+// FindAsset stats an object by key.
+func (u *s3UploaderRepository) FindAsset(ctx context.Context, publicID string) (model.Asset, error) {
+	bucket, info, err := u.stat(ctx, publicID)
+	if err != nil {
+		return model.Asset{}, err
+	}
+
+	return model.Asset{
+		PublicID:  publicID,
+		URL:       u.objectURL(bucket, publicID),
+		Bytes:     info.Size,
+		CreatedAt: info.LastModified,
+	}, nil
+}
+
+// List lists every object whose key starts with prefix, in the public and
+// private buckets.
+func (u *s3UploaderRepository) List(ctx context.Context, prefix string) ([]model.Asset, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var assets []model.Asset
+
+	for _, bucket := range u.buckets() {
+		for object := range u.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		}) {
+			if object.Err != nil {
+				logrus.WithField("prefix", prefix).WithError(object.Err).Error("failed to list objects")
+				return nil, object.Err
+			}
+
+			assets = append(assets, model.Asset{
+				PublicID:  object.Key,
+				URL:       u.objectURL(bucket, object.Key),
+				Bytes:     object.Size,
+				CreatedAt: object.LastModified,
+			})
+		}
+	}
+
+	return assets, nil
+}
+
+// Open reads an object by key.
+func (u *s3UploaderRepository) Open(ctx context.Context, publicID string) (io.ReadCloser, error) {
+	bucket, _, err := u.stat(ctx, publicID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.client.GetObject(ctx, bucket, publicID, minio.GetObjectOptions{})
+}
+
+// stat finds an object in the public bucket, or failing that the private
+// one, and returns the bucket holding it.
+func (u *s3UploaderRepository) stat(ctx context.Context, key string) (string, minio.ObjectInfo, error) {
+	for _, bucket := range u.buckets() {
+		info, err := u.client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			continue
+		}
+
+		if err != nil {
+			return "", minio.ObjectInfo{}, err
+		}
+
+		return bucket, info, nil
+	}
+
+	return "", minio.ObjectInfo{}, model.ErrAssetNotFound
+}
+
+// buckets are the buckets objects are stored in, public first.
+func (u *s3UploaderRepository) buckets() []string {
+	if u.config.PrivateBucket == u.config.Bucket {
+		return []string{u.config.Bucket}
+	}
+
+	return []string{u.config.Bucket, u.config.PrivateBucket}
+}
+
+func (u *s3UploaderRepository) srcURL(key string) string {
+	if u.config.PublicURL != "" {
+		return u.config.PublicURL + "/" + key
+	}
+
+	return u.config.AssetURL + "/" + key
+}
+
+// objectURL returns the delivery URL of an object in bucket. Private objects
+// are only delivered through the asset route.
+func (u *s3UploaderRepository) objectURL(bucket, key string) string {
+	if bucket != u.config.Bucket {
+		return u.config.AssetURL + "/" + key
+	}
+
+	return u.srcURL(key)
+}
+
+func (u *s3UploaderRepository) removeObjects(ctx context.Context, bucket string, objects <-chan minio.ObjectInfo) error {
+	var firstErr error
+
+	for removeErr := range u.client.RemoveObjects(ctx, bucket, objects, minio.RemoveObjectsOptions{}) {
+		if removeErr.Err == nil {
+			continue
+		}
+
+		logrus.WithField("key", removeErr.ObjectName).WithError(removeErr.Err).Error("failed to remove object")
+
+		if firstErr == nil {
+			firstErr = removeErr.Err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type uploadPurger struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+}
+
+// NewUploadPurger deletes a storage prefix together with its photo rows.
+func NewUploadPurger(db *gorm.DB, uploaderRepo model.UploaderRepository) model.UploadPurger {
+	return &uploadPurger{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+	}
+}
+
+// Preview counts what Purge would delete.
+func (p *uploadPurger) Preview(ctx context.Context, prefix string) (model.PurgeReport, error) {
+	logger := logrus.WithField("prefix", prefix)
+
+	report := model.PurgeReport{
+		DryRun:        true,
+		Prefix:        prefix,
+		ConfirmPhrase: model.PurgeConfirmPhrase(prefix),
+	}
+
+	assets, err := p.uploaderRepo.List(ctx, prefix)
+	if err != nil {
+		logger.WithError(err).Error("failed to list assets")
+		return model.PurgeReport{}, err
+	}
+
+	report.Assets = len(assets)
+
+	if err := p.db.
+		WithContext(ctx).
+		Model(&model.Photo{}).
+		Where("starts_with(public_id, ?)", prefix).
+		Count(&report.Photos).Error; err != nil {
+		logger.WithError(err).Error("failed to count photos")
+		return model.PurgeReport{}, err
+	}
+
+	return report, nil
+}
+
+// Purge deletes the photo rows under prefix and records the purge in one
+// transaction, then deletes the stored files. Files left behind by a storage
+// failure are picked up by the asset reconciler.
+func (p *uploadPurger) Purge(ctx context.Context, actorID, prefix string) (model.PurgeReport, error) {
+	logger := logrus.WithFields(logrus.Fields{"actor_id": actorID, "prefix": prefix})
+
+	report, err := p.Preview(ctx, prefix)
+	if err != nil {
+		return model.PurgeReport{}, err
+	}
+
+	report.DryRun = false
+
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := releaseUsage(tx, "starts_with(public_id, ?)", prefix); err != nil {
+			return err
+		}
+
+		result := tx.Where("starts_with(public_id, ?)", prefix).Delete(&model.Photo{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		report.Photos = result.RowsAffected
+
+		return tx.Create(&model.AuditLog{
+			ID:      ulid.Make().String(),
+			ActorID: actorID,
+			Action:  model.AuditActionUploadPurge,
+			Target:  prefix,
+			Details: map[string]interface{}{
+				"assets": report.Assets,
+				"photos": report.Photos,
+			},
+			CreatedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to delete photos")
+		return model.PurgeReport{}, err
+	}
+
+	if err := p.uploaderRepo.DeleteByPrefix(ctx, prefix); err != nil {
+		logger.WithError(err).Error("failed to delete assets")
+		return report, err
+	}
+
+	return report, nil
+}
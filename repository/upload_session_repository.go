@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type uploadSessionRepository struct {
+	db  *gorm.DB
+	dir string
+}
+
+// NewUploadSessionRepository stores session state in postgres and chunk data
+// under dir.
+func NewUploadSessionRepository(db *gorm.DB, dir string) model.UploadSessionRepository {
+	return &uploadSessionRepository{
+		db:  db,
+		dir: dir,
+	}
+}
+
+func (r *uploadSessionRepository) Create(ctx context.Context, session model.UploadSession) error {
+	logger := logrus.WithField("id", session.ID)
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		logger.WithError(err).Error("failed to create upload dir")
+		return err
+	}
+
+	file, err := os.Create(r.chunkPath(session.ID))
+	if err != nil {
+		logger.WithError(err).Error("failed to create chunk file")
+		return err
+	}
+	file.Close()
+
+	if err := r.db.WithContext(ctx).Create(&session).Error; err != nil {
+		logger.WithError(err).Error("failed to create upload session")
+		os.Remove(r.chunkPath(session.ID))
+		return err
+	}
+
+	return nil
+}
+
+func (r *uploadSessionRepository) FindByID(ctx context.Context, id string) (model.UploadSession, error) {
+	var session model.UploadSession
+
+	if err := r.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		First(&session).Error; err != nil {
+		return model.UploadSession{}, err
+	}
+
+	return session, nil
+}
+
+// Append writes chunk at the session offset and returns the new offset. It
+// fails with model.ErrUploadOffsetMismatch when another request moved the
+// offset first.
+func (r *uploadSessionRepository) Append(ctx context.Context, session model.UploadSession, chunk io.Reader, expiresAt time.Time) (int64, error) {
+	logger := logrus.WithField("id", session.ID)
+
+	var offset int64
+	var copyErr error
+
+	// Locking the row at the expected offset before writing makes a
+	// concurrent PATCH wait, then lose on the offset instead of
+	// interleaving its bytes into the chunk file.
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked model.UploadSession
+
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND upload_offset = ?", session.ID, session.Offset).
+			Limit(1).
+			Find(&locked)
+		if result.Error != nil {
+			logger.WithError(result.Error).Error("failed to lock upload session")
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return model.ErrUploadOffsetMismatch
+		}
+
+		file, err := os.OpenFile(r.chunkPath(session.ID), os.O_WRONLY, 0o644)
+		if err != nil {
+			logger.WithError(err).Error("failed to open chunk file")
+			return err
+		}
+		defer file.Close()
+
+		if _, err := file.Seek(session.Offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		// A dropped connection still keeps the bytes that arrived.
+		var written int64
+		written, copyErr = io.Copy(file, io.LimitReader(chunk, session.Length-session.Offset))
+		offset = session.Offset + written
+		if written == 0 {
+			return nil
+		}
+
+		if err := tx.
+			Model(&model.UploadSession{}).
+			Where("id = ?", session.ID).
+			Updates(map[string]interface{}{
+				"upload_offset": offset,
+				"expires_at":    expiresAt,
+				"updated_at":    time.Now(),
+			}).Error; err != nil {
+			logger.WithError(err).Error("failed to update upload offset")
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return session.Offset, err
+	}
+
+	return offset, copyErr
+}
+
+func (r *uploadSessionRepository) ReadAll(ctx context.Context, id string) ([]byte, error) {
+	return os.ReadFile(r.chunkPath(id))
+}
+
+func (r *uploadSessionRepository) Complete(ctx context.Context, id, photoID string) error {
+	logger := logrus.WithField("id", id)
+
+	if err := r.db.
+		WithContext(ctx).
+		Model(&model.UploadSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"photo_id":     photoID,
+			"completed_at": time.Now(),
+		}).Error; err != nil {
+		logger.WithError(err).Error("failed to complete upload session")
+		return err
+	}
+
+	return r.removeChunk(id)
+}
+
+func (r *uploadSessionRepository) Delete(ctx context.Context, id string) error {
+	if err := r.db.
+		WithContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.UploadSession{}).Error; err != nil {
+		logrus.WithField("id", id).WithError(err).Error("failed to delete upload session")
+		return err
+	}
+
+	return r.removeChunk(id)
+}
+
+// DeleteExpired removes sessions and chunk files past their expiry.
+func (r *uploadSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var ids []string
+
+	if err := r.db.
+		WithContext(ctx).
+		Model(&model.UploadSession{}).
+		Where("expires_at < ?", now).
+		Pluck("id", &ids).Error; err != nil {
+		logrus.WithError(err).Error("failed to find expired upload sessions")
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := r.Delete(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(ids)), nil
+}
+
+func (r *uploadSessionRepository) chunkPath(id string) string {
+	return filepath.Join(r.dir, filepath.Base(id))
+}
+
+func (r *uploadSessionRepository) removeChunk(id string) error {
+	if err := os.Remove(r.chunkPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logrus.WithField("id", id).WithError(err).Error("failed to remove chunk file")
+		return err
+	}
+
+	return nil
+}
@@ -2,27 +2,43 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
 	"github.com/cloudinary/cloudinary-go/v2/api/admin"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/notblessy/ekspresi-core/model"
-	"github.com/notblessy/ekspresi-core/utils"
-	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// assetTypes are the cloudinary asset types holding portfolio uploads.
+var assetTypes = []api.AssetType{api.Image, api.Video}
+
+// deliveryTypes are the cloudinary delivery types holding portfolio uploads.
+// Authenticated assets are only delivered through signed URLs.
+var deliveryTypes = []api.DeliveryType{api.Upload, api.Authenticated}
+
 type uploaderRepository struct {
+	photoStore
 	cloudinary *cloudinary.Cloudinary
-	db         *gorm.DB
+	assetURL   string
 }
 
-// NewUploaderRepository creates a new instance of uploader.
-func NewUploaderRepository(cloudinary *cloudinary.Cloudinary, db *gorm.DB) model.UploaderRepository {
+// NewUploaderRepository creates a new instance of uploader. assetURL is the
+// base URL of the asset route delivering private files.
+func NewUploaderRepository(cloudinary *cloudinary.Cloudinary, assetURL string, db *gorm.DB) model.UploaderRepository {
 	return &uploaderRepository{
+		photoStore: photoStore{db: db},
 		cloudinary: cloudinary,
-		db:         db,
+		assetURL:   strings.TrimSuffix(assetURL, "/"),
 	}
 }
 
@@ -40,54 +56,292 @@ func (u *uploaderRepository) Upload(ctx context.Context, file io.Reader, path st
 	return uploadResult.SecureURL, uploadResult.PublicID, nil
 }
 
-// DeleteByPublicIDs deletes a file from cloudinary by public IDs.
-func (u *uploaderRepository) DeleteByPublicIDs(ctx context.Context, publicIDs []string) error {
-	_, err := u.cloudinary.Admin.DeleteAssets(ctx, admin.DeleteAssetsParams{
-		PublicIDs: publicIDs,
-		AssetType: "image",
+// UploadPrivate uploads a file to cloudinary as an authenticated asset, which
+// has no public delivery URL.
+func (u *uploaderRepository) UploadPrivate(ctx context.Context, file io.Reader, path string) (string, string, error) {
+	uploadResult, err := u.cloudinary.Upload.Upload(ctx, file, uploader.UploadParams{
+		Folder: path,
+		Type:   api.Authenticated,
 	})
+	if err != nil {
+		return "", "", err
+	}
 
-	return err
+	return u.assetURL + "/" + uploadResult.PublicID, uploadResult.PublicID, nil
 }
 
-// Flush deletes all files from cloudinary.
-func (u *uploaderRepository) Flush(ctx context.Context) error {
-	allParam := true
+// UploadVariant uploads a rendered variant to cloudinary without converting
+// it, so its stored format matches the rendered one.
+func (u *uploaderRepository) UploadVariant(ctx context.Context, file io.Reader, path, format string) (string, string, error) {
+	uploadResult, err := u.cloudinary.Upload.Upload(ctx, file, uploader.UploadParams{
+		Folder: path,
+	})
+	if err != nil {
+		return "", "", err
+	}
 
-	_, err := u.cloudinary.Admin.DeleteAllAssets(ctx, admin.DeleteAllAssetsParams{
-		All: &allParam,
+	return uploadResult.SecureURL, uploadResult.PublicID, nil
+}
+
+// UploadVideo uploads a clip to cloudinary as a video asset.
+func (u *uploaderRepository) UploadVideo(ctx context.Context, file io.Reader, path string) (string, string, error) {
+	uploadResult, err := u.cloudinary.Upload.Upload(ctx, file, uploader.UploadParams{
+		Folder:       path,
+		ResourceType: "video",
 	})
 	if err != nil {
-		return err
+		return "", "", err
+	}
+
+	return uploadResult.SecureURL, uploadResult.PublicID, nil
+}
+
+// DeleteByPublicIDs deletes a file from cloudinary by public IDs. Cloudinary
+// scopes public IDs by asset and delivery type, so each is deleted in turn.
+// API failures such as rate limits come back in the result, not as err.
+func (u *uploaderRepository) DeleteByPublicIDs(ctx context.Context, publicIDs []string) error {
+	for _, assetType := range assetTypes {
+		for _, deliveryType := range deliveryTypes {
+			result, err := u.cloudinary.Admin.DeleteAssets(ctx, admin.DeleteAssetsParams{
+				PublicIDs:    publicIDs,
+				AssetType:    assetType,
+				DeliveryType: deliveryType,
+			})
+			if err != nil {
+				return err
+			}
+
+			if result.Error.Message != "" {
+				return fmt.Errorf("failed to delete assets: %s", result.Error.Message)
+			}
+		}
 	}
 
 	return nil
 }
 
-// SavePhoto saves a photo to the database.
-func (u *uploaderRepository) SavePhoto(ctx context.Context, photo model.Photo) error {
-	logger := logrus.WithField("photo", utils.Dump(photo))
+// DeleteByPrefix deletes every image and video whose public ID starts with
+// prefix. Cloudinary deletes in pages, so the call repeats while results are
+// partial.
+func (u *uploaderRepository) DeleteByPrefix(ctx context.Context, prefix string) error {
+	for _, assetType := range assetTypes {
+		for _, deliveryType := range deliveryTypes {
+			cursor := ""
 
-	err := u.db.Save(&photo).Error
-	if err != nil {
-		logger.WithError(err).Error("failed to save photo")
-		return err
+			for {
+				result, err := u.cloudinary.Admin.DeleteAssetsByPrefix(ctx, admin.DeleteAssetsByPrefixParams{
+					AssetType:    assetType,
+					DeliveryType: deliveryType,
+					Prefix:       api.CldAPIArray{prefix},
+					NextCursor:   cursor,
+				})
+				if err != nil {
+					return err
+				}
+
+				if result.Error.Message != "" {
+					return fmt.Errorf("failed to delete assets: %s", result.Error.Message)
+				}
+
+				if !result.Partial {
+					break
+				}
+
+				cursor = result.NextCursor
+			}
+		}
 	}
 
 	return nil
 }
 
-// FindByPublicIDs finds a photo by public IDs.
-func (u *uploaderRepository) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]model.Photo, error) {
-	logger := logrus.WithField("public_ids", publicIDs)
+// URL returns the delivery URL of a cloudinary asset.
+func (u *uploaderRepository) URL(ctx context.Context, publicID string) (string, error) {
+	asset, err := u.cloudinary.Image(publicID)
+	if err != nil {
+		return "", err
+	}
+
+	return asset.String()
+}
+
+// SignUpload signs upload parameters restricted to path. Cloudinary accepts a
+// signature for one hour after its timestamp, so expiry is capped there.
+func (u *uploaderRepository) SignUpload(ctx context.Context, path string, expiry time.Duration) (model.SignedUpload, error) {
+	now := time.Now()
+
+	params := url.Values{
+		"folder":    {path},
+		"timestamp": {strconv.FormatInt(now.Unix(), 10)},
+	}
+
+	signature, err := api.SignParameters(params, u.cloudinary.Config.Cloud.APISecret)
+	if err != nil {
+		return model.SignedUpload{}, err
+	}
+
+	if expiry > time.Hour {
+		expiry = time.Hour
+	}
+
+	return model.SignedUpload{
+		URL:    fmt.Sprintf("%s/v1_1/%s/image/upload", u.cloudinary.Config.API.UploadPrefix, u.cloudinary.Config.Cloud.CloudName),
+		Method: http.MethodPost,
+		Fields: map[string]string{
+			"api_key":   u.cloudinary.Config.Cloud.APIKey,
+			"folder":    path,
+			"timestamp": params.Get("timestamp"),
+			"signature": signature,
+		},
+		ExpiresAt: now.Add(expiry),
+	}, nil
+}
+
+// FindAsset finds a cloudinary image, or failing that a video, by public ID.
+func (u *uploaderRepository) FindAsset(ctx context.Context, publicID string) (model.Asset, error) {
+	var message string
+
+	for _, assetType := range assetTypes {
+		for _, deliveryType := range deliveryTypes {
+			result, err := u.cloudinary.Admin.Asset(ctx, admin.AssetParams{
+				PublicID:     publicID,
+				AssetType:    assetType,
+				DeliveryType: deliveryType,
+			})
+			if err != nil {
+				return model.Asset{}, err
+			}
+
+			if result.Error.Message != "" || result.PublicID == "" {
+				message = result.Error.Message
+				continue
+			}
+
+			return model.Asset{
+				PublicID:  result.PublicID,
+				URL:       result.SecureURL,
+				Bytes:     int64(result.Bytes),
+				Width:     result.Width,
+				Height:    result.Height,
+				CreatedAt: result.CreatedAt,
+			}, nil
+		}
+	}
+
+	return model.Asset{}, fmt.Errorf("%w: %s", model.ErrAssetNotFound, message)
+}
+
+// List lists every uploaded image and video whose public ID starts with
+// prefix.
+func (u *uploaderRepository) List(ctx context.Context, prefix string) ([]model.Asset, error) {
+	var assets []model.Asset
+
+	for _, assetType := range assetTypes {
+		for _, deliveryType := range deliveryTypes {
+			cursor := ""
+
+			for {
+				result, err := u.cloudinary.Admin.Assets(ctx, admin.AssetsParams{
+					AssetType:    assetType,
+					DeliveryType: string(deliveryType),
+					Prefix:       prefix,
+					MaxResults:   500,
+					NextCursor:   cursor,
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				if result.Error.Message != "" {
+					return nil, fmt.Errorf("failed to list assets: %s", result.Error.Message)
+				}
+
+				for _, a := range result.Assets {
+					assets = append(assets, model.Asset{
+						PublicID:  a.PublicID,
+						URL:       a.SecureURL,
+						Bytes:     int64(a.Bytes),
+						Width:     a.Width,
+						Height:    a.Height,
+						CreatedAt: a.CreatedAt,
+					})
+				}
+
+				if result.NextCursor == "" {
+					break
+				}
+
+				cursor = result.NextCursor
+			}
+		}
+	}
+
+	return assets, nil
+}
+
+// Open downloads an asset through its delivery URL, trying a signed URL for
+// private images and then the video URL when no image has publicID.
+func (u *uploaderRepository) Open(ctx context.Context, publicID string) (io.ReadCloser, error) {
+	src, err := u.URL(ctx, publicID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := u.download(ctx, publicID, src)
+	if !errors.Is(err, model.ErrAssetNotFound) {
+		return body, err
+	}
+
+	private, err := u.cloudinary.Image(publicID)
+	if err != nil {
+		return nil, err
+	}
+
+	private.DeliveryType = api.Authenticated
+	private.Config.URL.SignURL = true
+
+	if src, err = private.String(); err != nil {
+		return nil, err
+	}
+
+	body, err = u.download(ctx, publicID, src)
+	if !errors.Is(err, model.ErrAssetNotFound) {
+		return body, err
+	}
 
-	var photos []model.Photo
+	video, err := u.cloudinary.Video(publicID)
+	if err != nil {
+		return nil, err
+	}
 
-	err := u.db.Where("public_id IN (?)", publicIDs).Find(&photos).Error
+	src, err = video.String()
 	if err != nil {
-		logger.WithError(err).Error("failed to find photos")
 		return nil, err
 	}
 
-	return photos, nil
+	return u.download(ctx, publicID, src)
+}
+
+func (u *uploaderRepository) download(ctx context.Context, publicID, src string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, model.ErrAssetNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", publicID, resp.Status)
+	}
+
+	return resp.Body, nil
 }
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type usageRepository struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+}
+
+func NewUsageRepository(db *gorm.DB, uploaderRepo model.UploaderRepository) model.UsageRepository {
+	return &usageRepository{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+	}
+}
+
+// FindByUserID returns the user's usage, zero when nothing was stored yet.
+func (r *usageRepository) FindByUserID(ctx context.Context, userID string) (model.StorageUsage, error) {
+	usage := model.StorageUsage{UserID: userID}
+
+	err := r.db.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&usage).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logrus.WithField("user_id", userID).WithError(err).Error("failed to find storage usage")
+		return model.StorageUsage{}, err
+	}
+
+	return usage, nil
+}
+
+// Backfill sizes photos of unknown size from the storage backend and rebuilds
+// the usage totals from photos.bytes. Photos whose assets cannot be looked up
+// are skipped and logged, so a failing backend never wipes stored sizes. It
+// returns the number of photos sized.
+func (r *usageRepository) Backfill(ctx context.Context) (int, error) {
+	var photos []model.Photo
+
+	sized := 0
+
+	err := r.db.
+		WithContext(ctx).
+		Preload("Variants").
+		Where("bytes = 0").
+		FindInBatches(&photos, 100, func(tx *gorm.DB, batch int) error {
+			for _, photo := range photos {
+				bytes, err := r.photoBytes(ctx, photo)
+				if err != nil {
+					logrus.WithField("photo_id", photo.ID).WithError(err).Warn("failed to size photo")
+					continue
+				}
+
+				if err := r.db.
+					WithContext(ctx).
+					Model(&model.Photo{}).
+					Where("id = ?", photo.ID).
+					Update("bytes", bytes).Error; err != nil {
+					return err
+				}
+
+				sized++
+			}
+
+			return nil
+		}).Error
+	if err != nil {
+		logrus.WithError(err).Error("failed to size photos")
+		return sized, err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			INSERT INTO storage_usages (user_id, bytes, photos, updated_at)
+			SELECT user_id, SUM(bytes), COUNT(*), NOW() FROM photos
+			WHERE user_id IS NOT NULL
+			GROUP BY user_id
+			ON CONFLICT (user_id) DO UPDATE
+			SET bytes = EXCLUDED.bytes, photos = EXCLUDED.photos, updated_at = EXCLUDED.updated_at`).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			UPDATE storage_usages SET bytes = 0, photos = 0, updated_at = NOW()
+			WHERE user_id NOT IN (SELECT user_id FROM photos WHERE user_id IS NOT NULL)`).Error
+	})
+	if err != nil {
+		logrus.WithError(err).Error("failed to rebuild storage usage")
+		return sized, err
+	}
+
+	return sized, nil
+}
+
+// photoBytes sums the stored sizes of a photo's original, poster and
+// variants.
+func (r *usageRepository) photoBytes(ctx context.Context, photo model.Photo) (int64, error) {
+	var total int64
+
+	for _, publicID := range []string{photo.PublicID, photo.PosterPublicID} {
+		bytes, err := r.assetBytes(ctx, publicID)
+		if err != nil {
+			return 0, err
+		}
+
+		total += bytes
+	}
+
+	for _, v := range photo.Variants {
+		if v.Bytes == 0 {
+			bytes, err := r.assetBytes(ctx, v.PublicID)
+			if err != nil {
+				return 0, err
+			}
+
+			v.Bytes = bytes
+		}
+
+		total += v.Bytes
+	}
+
+	return total, nil
+}
+
+// assetBytes returns the stored size of an asset, zero when it is missing.
+func (r *usageRepository) assetBytes(ctx context.Context, publicID string) (int64, error) {
+	if publicID == "" {
+		return 0, nil
+	}
+
+	asset, err := r.uploaderRepo.FindAsset(ctx, publicID)
+	if errors.Is(err, model.ErrAssetNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return asset.Bytes, nil
+}
+
+// addUsage adjusts a user's running totals, never below zero.
+func addUsage(tx *gorm.DB, userID string, bytes int64, photos int) error {
+	if userID == "" || (bytes == 0 && photos == 0) {
+		return nil
+	}
+
+	return tx.Exec(`
+		INSERT INTO storage_usages (user_id, bytes, photos, updated_at)
+		VALUES (?, GREATEST(?::BIGINT, 0), GREATEST(?::INT, 0), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET bytes = GREATEST(storage_usages.bytes + ?, 0),
+			photos = GREATEST(storage_usages.photos + ?, 0),
+			updated_at = NOW()`,
+		userID, bytes, photos, bytes, photos).Error
+}
+
+// releaseUsage subtracts the photos matching query from their owners' totals.
+// Call it before the photos are deleted.
+func releaseUsage(tx *gorm.DB, query interface{}, args ...interface{}) error {
+	var owners []struct {
+		UserID string
+		Bytes  int64
+		Photos int
+	}
+
+	if err := tx.
+		Model(&model.Photo{}).
+		Select("user_id, COALESCE(SUM(bytes), 0) AS bytes, COUNT(*) AS photos").
+		Where(query, args...).
+		Where("user_id IS NOT NULL").
+		Group("user_id").
+		Scan(&owners).Error; err != nil {
+		return err
+	}
+
+	for _, o := range owners {
+		if err := addUsage(tx, o.UserID, -o.Bytes, -o.Photos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
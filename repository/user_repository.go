@@ -117,6 +117,9 @@ func (a *userRepository) FindByID(ctx context.Context, id string) (model.MeRespo
 		Preload("Portfolio.Profiles").
 		Preload("Portfolio.Folders").
 		Preload("Portfolio.Folders.Photos").
+		Preload("Portfolio.Folders.Photos.Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("width ASC")
+		}).
 		First(&user).Error
 	if err != nil {
 		logger.Errorf("Error querying user: %v", err)
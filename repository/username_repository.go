@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type usernameRepository struct {
+	db             *gorm.DB
+	redirectPeriod time.Duration
+}
+
+// NewUsernameRepository keeps released usernames redirecting and reserved
+// for redirectPeriod.
+func NewUsernameRepository(db *gorm.DB, redirectPeriod time.Duration) model.UsernameRepository {
+	return &usernameRepository{
+		db:             db,
+		redirectPeriod: redirectPeriod,
+	}
+}
+
+// Change gives the user a validated, normalized username. Their previous one
+// goes to the history; claims of the same name are serialized with an
+// advisory lock so the checks below cannot race.
+func (r *usernameRepository) Change(ctx context.Context, userID, username string) (model.User, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"username": username,
+	})
+
+	var user model.User
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "username:"+username).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		if user.Username == username {
+			return nil
+		}
+
+		var taken int64
+
+		if err := tx.Model(&model.User{}).
+			Where("LOWER(username) = ? AND id <> ?", username, userID).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+
+		if taken > 0 {
+			return model.ErrUsernameTaken
+		}
+
+		if err := tx.Model(&model.UsernameHistory{}).
+			Where("LOWER(username) = ? AND user_id <> ? AND expires_at > ?", username, userID, time.Now()).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+
+		if taken > 0 {
+			return model.ErrUsernameTaken
+		}
+
+		// Taking back an old name ends its redirect.
+		if err := tx.Where("user_id = ? AND LOWER(username) = ?", userID, username).Delete(&model.UsernameHistory{}).Error; err != nil {
+			return err
+		}
+
+		if user.Username != "" {
+			if err := tx.Create(&model.UsernameHistory{
+				ID:        ulid.Make().String(),
+				UserID:    userID,
+				Username:  model.NormalizeUsername(user.Username),
+				CreatedAt: time.Now(),
+				ExpiresAt: time.Now().Add(r.redirectPeriod),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Update("username", username).Error; err != nil {
+			return err
+		}
+
+		user.Username = username
+
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to change username")
+		return model.User{}, err
+	}
+
+	user.OmitPassword()
+
+	return user, nil
+}
+
+// FindRedirect returns the current username of whoever released username
+// within the redirect period.
+func (r *usernameRepository) FindRedirect(ctx context.Context, username string) (string, error) {
+	var current string
+
+	err := r.db.WithContext(ctx).
+		Model(&model.UsernameHistory{}).
+		Select("users.username").
+		Joins("JOIN users ON users.id = username_history.user_id").
+		Where("LOWER(username_history.username) = LOWER(?) AND username_history.expires_at > ?", username, time.Now()).
+		Where("users.username <> '' AND users.deleted_at IS NULL").
+		Order("username_history.created_at DESC").
+		Limit(1).
+		Scan(&current).Error
+	if err != nil {
+		logrus.WithField("username", username).WithError(err).Error("failed to find username redirect")
+		return "", err
+	}
+
+	if current == "" {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return current, nil
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type variantRenderer struct {
+	db           *gorm.DB
+	uploaderRepo model.UploaderRepository
+	pipeline     *imaging.Pipeline
+}
+
+// NewVariantRenderer renders variants with pipeline and stores them through
+// uploaderRepo.
+func NewVariantRenderer(db *gorm.DB, uploaderRepo model.UploaderRepository, pipeline *imaging.Pipeline) model.VariantRenderer {
+	return &variantRenderer{
+		db:           db,
+		uploaderRepo: uploaderRepo,
+		pipeline:     pipeline,
+	}
+}
+
+// Render renders the variants of buf and uploads them to folder. Already
+// uploaded variants are queued for deletion when one fails.
+func (r *variantRenderer) Render(ctx context.Context, buf []byte, folder, photoID string, settings *model.WatermarkSettings) ([]model.PhotoVariant, error) {
+	watermark, err := r.loadWatermark(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := r.pipeline.Process(buf, watermark)
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []model.PhotoVariant
+
+	for _, v := range rendered {
+		url, publicID, err := r.uploaderRepo.UploadVariant(ctx, bytes.NewReader(v.Data), folder, v.Format)
+		if err != nil {
+			enqueueAssetDeletions(r.db.WithContext(ctx), variantPublicIDs(variants))
+			return nil, err
+		}
+
+		variants = append(variants, model.PhotoVariant{
+			ID:          ulid.Make().String(),
+			PhotoID:     photoID,
+			Name:        v.Name,
+			Format:      v.Format,
+			Src:         url,
+			PublicID:    publicID,
+			Width:       v.Width,
+			Height:      v.Height,
+			Bytes:       int64(len(v.Data)),
+			Watermarked: v.Watermarked,
+			CreatedAt:   time.Now(),
+		})
+	}
+
+	return variants, nil
+}
+
+// renderMaxAttempts is how many runs a photo is retried before its render
+// request is dropped.
+const renderMaxAttempts = 5
+
+// RenderPending re-renders up to limit photos whose portfolio watermark
+// changed. A photo that fails is retried on later runs after photos that have
+// not failed yet, and given up after renderMaxAttempts.
+func (r *variantRenderer) RenderPending(ctx context.Context, limit int) ([]model.Photo, error) {
+	var photos []model.Photo
+
+	if err := r.db.
+		WithContext(ctx).
+		Preload("Variants").
+		Where("render_requested_at IS NOT NULL AND media_type = ?", model.MediaTypeImage).
+		Order("render_attempts ASC, render_requested_at ASC").
+		Limit(limit).
+		Find(&photos).Error; err != nil {
+		logrus.WithError(err).Error("failed to find photos to render")
+		return nil, err
+	}
+
+	var rendered []model.Photo
+
+	for _, photo := range photos {
+		if err := r.rerender(ctx, photo); err != nil {
+			logrus.WithField("photo_id", photo.ID).WithError(err).Error("failed to render photo variants")
+			r.recordFailure(ctx, photo)
+			continue
+		}
+
+		rendered = append(rendered, photo)
+	}
+
+	return rendered, nil
+}
+
+// rerender replaces a photo's variants with ones rendered from its original.
+// The pending flag is only cleared when no newer request arrived meanwhile.
+func (r *variantRenderer) rerender(ctx context.Context, photo model.Photo) error {
+	startedAt := time.Now()
+
+	var portfolio model.Portfolio
+
+	if err := r.db.
+		WithContext(ctx).
+		Where("user_id = ?", photo.UserID).
+		First(&portfolio).Error; err != nil {
+		return err
+	}
+
+	file, err := r.uploaderRepo.Open(ctx, photo.PublicID)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	variants, err := r.Render(ctx, buf, path.Dir(photo.PublicID), photo.ID, portfolio.WatermarkSettings)
+	if err != nil {
+		return err
+	}
+
+	original := photo.OriginalSrc
+	if original == "" {
+		original = photo.Src
+	}
+
+	delta := variantBytes(variants) - variantBytes(photo.Variants)
+
+	updates := map[string]interface{}{
+		"src":          model.PublicSrc(original, variants),
+		"original_src": original,
+		"bytes":        gorm.Expr("bytes + ?", delta),
+	}
+
+	// Direct uploads reach the server for the first time here.
+	if photo.BlurHash == "" {
+		if placeholder, err := imaging.NewPlaceholder(buf); err == nil {
+			updates["blurhash"] = placeholder.BlurHash
+			updates["dominant_color"] = placeholder.DominantColor
+			updates["average_color"] = placeholder.AverageColor
+		}
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("photo_id = ?", photo.ID).Delete(&model.PhotoVariant{}).Error; err != nil {
+			return err
+		}
+
+		if len(variants) > 0 {
+			if err := tx.Create(&variants).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := enqueueAssetDeletions(tx, variantPublicIDs(photo.Variants)); err != nil {
+			return err
+		}
+
+		if err := tx.
+			Model(&model.Photo{}).
+			Where("id = ?", photo.ID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.
+			Model(&model.Photo{}).
+			Where("id = ? AND render_requested_at <= ?", photo.ID, startedAt).
+			Updates(map[string]interface{}{
+				"render_requested_at": nil,
+				"render_attempts":     0,
+			}).Error; err != nil {
+			return err
+		}
+
+		return addUsage(tx, photo.UserID, delta, 0)
+	})
+	if err != nil {
+		enqueueAssetDeletions(r.db.WithContext(ctx), variantPublicIDs(variants))
+		return err
+	}
+
+	return nil
+}
+
+// recordFailure counts a failed render and drops the request once the photo
+// has failed renderMaxAttempts times, so it stops taking a slot every run.
+func (r *variantRenderer) recordFailure(ctx context.Context, photo model.Photo) {
+	updates := map[string]interface{}{
+		"render_attempts": gorm.Expr("render_attempts + 1"),
+	}
+
+	if photo.RenderAttempts+1 >= renderMaxAttempts {
+		logrus.WithField("photo_id", photo.ID).Warn("giving up on photo variants")
+		updates["render_requested_at"] = nil
+	}
+
+	if err := r.db.
+		WithContext(ctx).
+		Model(&model.Photo{}).
+		Where("id = ?", photo.ID).
+		Updates(updates).Error; err != nil {
+		logrus.WithField("photo_id", photo.ID).WithError(err).Error("failed to record render failure")
+	}
+}
+
+func (r *variantRenderer) loadWatermark(ctx context.Context, settings *model.WatermarkSettings) (*imaging.Watermark, error) {
+	if !settings.Active() {
+		return nil, nil
+	}
+
+	watermark := &imaging.Watermark{
+		Text:     settings.Text,
+		Position: settings.Position,
+		Opacity:  settings.Opacity,
+		Scale:    settings.Scale,
+		Variants: settings.Variants,
+	}
+
+	if settings.LogoPublicID != "" {
+		file, err := r.uploaderRepo.Open(ctx, settings.LogoPublicID)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		if watermark.Logo, err = io.ReadAll(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return watermark, nil
+}
+
+func variantPublicIDs(variants []model.PhotoVariant) []string {
+	var publicIDs []string
+
+	for _, v := range variants {
+		publicIDs = append(publicIDs, v.PublicID)
+	}
+
+	return publicIDs
+}
+
+func variantBytes(variants []model.PhotoVariant) int64 {
+	var total int64
+
+	for _, v := range variants {
+		total += v.Bytes
+	}
+
+	return total
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *httpService) assetDeletionStatsHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get auth session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if session.Role != model.RoleAdmin {
+		return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+	}
+
+	stats, err := h.assetDeletionRepo.Stats(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: stats})
+}
+
+func (h *httpService) retryAssetDeletionsHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get auth session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if session.Role != model.RoleAdmin {
+		return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+	}
+
+	requeued, err := h.assetDeletionRepo.RetryDead(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: map[string]int64{"requeued": requeued}})
+}
+
+// metricsHandler serves the expvar counters to admins.
+func (h *httpService) metricsHandler(c echo.Context) error {
+	session, err := authSession(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if session.Role != model.RoleAdmin {
+		return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+	}
+
+	expvar.Handler().ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
@@ -64,6 +64,22 @@ func (m *JWTMiddleware) ValidateJWT(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// OptionalJWT sets the session when a valid token is sent and otherwise
+// serves the request anonymously.
+func (m *JWTMiddleware) OptionalJWT(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+		if token != "" {
+			if user, err := validateToken(token); err == nil && user.ID != "" {
+				c.Set("user", user)
+			}
+		}
+
+		return next(c)
+	}
+}
+
 func validateToken(tokenString string) (jwtClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/notblessy/ekspresi-core/model"
+)
+
+// checkOwnership returns model.ErrForbidden when a resource of kind in ids
+// belongs to someone other than userID. Unknown ids pass since clients pick
+// the ids of rows they create.
+func (h *httpService) checkOwnership(ctx context.Context, userID, kind string, ids ...string) error {
+	var lookup []string
+
+	for _, id := range ids {
+		if id != "" {
+			lookup = append(lookup, id)
+		}
+	}
+
+	if len(lookup) == 0 {
+		return nil
+	}
+
+	owners, err := h.ownershipRepo.FindOwners(ctx, kind, lookup)
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		if owner != userID {
+			return model.ErrForbidden
+		}
+	}
+
+	return nil
+}
+
+// authorize is checkOwnership for the session user, returning the status to
+// respond with. Admins may act on any resource.
+func (h *httpService) authorize(ctx context.Context, session jwtClaims, kind string, ids ...string) (int, error) {
+	if session.Role == model.RoleAdmin {
+		return 0, nil
+	}
+
+	if err := h.checkOwnership(ctx, session.ID, kind, ids...); err != nil {
+		if errors.Is(err, model.ErrForbidden) {
+			return http.StatusForbidden, err
+		}
+
+		return http.StatusInternalServerError, err
+	}
+
+	return 0, nil
+}
+
+// checkPhotoOwnership rejects a new photo whose client-chosen ID or folder
+// belongs to another user.
+func (h *httpService) checkPhotoOwnership(ctx context.Context, userID string, photo model.Photo) error {
+	if err := h.checkOwnership(ctx, userID, model.ResourcePhoto, photo.ID); err != nil {
+		return err
+	}
+
+	return h.checkOwnership(ctx, userID, model.ResourceFolder, photo.FolderID)
+}
+
+// ownsPublicID reports whether publicID lies in the user's upload folder.
+func ownsPublicID(userID, publicID string) bool {
+	prefix := strings.Trim(uploadPath(userID), "/") + "/"
+
+	return strings.HasPrefix(publicID, prefix) && path.Clean(publicID) == publicID
+}
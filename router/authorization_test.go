@@ -0,0 +1,405 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	ownerID    = "owner"
+	intruderID = "intruder"
+)
+
+type fakeOwnershipRepository struct {
+	owners map[string]map[string]string
+}
+
+func (f fakeOwnershipRepository) FindOwners(ctx context.Context, kind string, ids []string) (map[string]string, error) {
+	owners := map[string]string{}
+
+	for _, id := range ids {
+		if owner, ok := f.owners[kind][id]; ok {
+			owners[id] = owner
+		}
+	}
+
+	return owners, nil
+}
+
+// fakeUploadSessionRepository only implements FindByID; other methods panic.
+type fakeUploadSessionRepository struct {
+	model.UploadSessionRepository
+	sessions map[string]model.UploadSession
+}
+
+func (f fakeUploadSessionRepository) FindByID(ctx context.Context, id string) (model.UploadSession, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return model.UploadSession{}, gorm.ErrRecordNotFound
+	}
+
+	return session, nil
+}
+
+// fakePortfolioRepository only implements the portfolio, folder and published
+// portfolio lookups, counting the latter, and accepts patches without storing
+// them; other methods panic.
+type fakePortfolioRepository struct {
+	model.PortfolioRepository
+	portfolios map[string]model.Portfolio
+	folders    map[string]model.Folder
+	published  map[string]model.PortfolioType
+	lookups    int
+}
+
+func (f *fakePortfolioRepository) Patch(ctx context.Context, input model.PortfolioType) error {
+	return nil
+}
+
+func (f *fakePortfolioRepository) FindByID(ctx context.Context, id string) (model.Portfolio, error) {
+	portfolio, ok := f.portfolios[id]
+	if !ok {
+		return model.Portfolio{}, gorm.ErrRecordNotFound
+	}
+
+	return portfolio, nil
+}
+
+func (f *fakePortfolioRepository) FindPublishedByUsername(ctx context.Context, username string) (model.PortfolioType, error) {
+	f.lookups++
+
+	portfolio, ok := f.published[username]
+	if !ok {
+		return model.PortfolioType{}, gorm.ErrRecordNotFound
+	}
+
+	return portfolio, nil
+}
+
+func (f *fakePortfolioRepository) FindFolderByID(ctx context.Context, id string) (model.Folder, error) {
+	folder, ok := f.folders[id]
+	if !ok {
+		return model.Folder{}, gorm.ErrRecordNotFound
+	}
+
+	return folder, nil
+}
+
+// fakeUsernameRepository redirects the released usernames in redirects;
+// other methods panic.
+type fakeUsernameRepository struct {
+	model.UsernameRepository
+	redirects map[string]string
+}
+
+func (f fakeUsernameRepository) FindRedirect(ctx context.Context, username string) (string, error) {
+	current, ok := f.redirects[username]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return current, nil
+}
+
+var downloadAllowed = true
+
+// newTestService wires only what is needed to reach the authorization checks.
+// A handler touching anything else panics, which fails the test.
+func newTestService() (*echo.Echo, *httpService) {
+	h := NewHTTPService()
+	h.RegisterOwnershipRepository(fakeOwnershipRepository{owners: map[string]map[string]string{
+		model.ResourcePortfolio: {"portfolio-owner": ownerID},
+		model.ResourceProfile:   {"profile-owner": ownerID},
+		model.ResourceFolder:    {"folder-owner": ownerID, "folder-shared": ownerID},
+		model.ResourcePhoto:     {"photo-owner": ownerID},
+		model.ResourceAsset:     {"portfolios/owner/photo.jpg": ownerID},
+		model.ResourceDomain:    {"domain-owner": ownerID},
+	}})
+	h.RegisterUploadSessionRepository(fakeUploadSessionRepository{sessions: map[string]model.UploadSession{
+		"upload-owner": {ID: "upload-owner", UserID: ownerID},
+	}})
+
+	h.RegisterUsernameRepository(fakeUsernameRepository{})
+	h.RegisterPortfolioRepository(&fakePortfolioRepository{
+		portfolios: map[string]model.Portfolio{
+			"portfolio-owner": {ID: "portfolio-owner", UserID: ownerID},
+		},
+		folders: map[string]model.Folder{
+			"folder-owner":  {ID: "folder-owner", PortfolioID: "portfolio-owner"},
+			"folder-shared": {ID: "folder-shared", PortfolioID: "portfolio-owner", AllowDownload: &downloadAllowed},
+		},
+	})
+
+	e := echo.New()
+	e.Validator = &utils.Ghost{Validator: validator.New()}
+	h.Router(e)
+
+	return e, h
+}
+
+func testToken(t *testing.T, userID, role string) string {
+	t.Helper()
+
+	token, err := signJwtToken(userID, userID, role)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return token
+}
+
+func multipartBody(t *testing.T, fields map[string]string) (io.Reader, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w := multipart.NewWriter(&buf)
+
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+
+	part, err := w.CreateFormFile("file", "photo.jpg")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+
+	part.Write([]byte("not an image"))
+	w.Close()
+
+	return &buf, w.FormDataContentType()
+}
+
+type routeCase struct {
+	name   string
+	method string
+	route  string
+	path   string
+	body   string
+	header map[string]string
+	form   map[string]string
+	// public routes are served without a token.
+	public bool
+	// want is the status an intruder gets. Zero skips the request for routes
+	// that only act on the caller's own data and need real dependencies.
+	want int
+}
+
+var tusHeader = map[string]string{"Tus-Resumable": model.TusVersion}
+
+func routeCases() []routeCase {
+	return []routeCase{
+		{name: "ping", method: http.MethodGet, route: "/ping", path: "/ping", public: true, want: http.StatusOK},
+		{name: "health", method: http.MethodGet, route: "/health", public: true},
+		{name: "google login", method: http.MethodPost, route: "/api/v1/auth/login/google", public: true},
+		{name: "asset", method: http.MethodGet, route: "/api/v1/assets/*", public: true},
+		{name: "unknown public portfolio", method: http.MethodGet, route: "/api/v1/public/portfolios/:username", path: "/api/v1/public/portfolios/nobody", public: true, want: http.StatusNotFound},
+		{name: "download private folder", method: http.MethodGet, route: "/api/v1/folders/:id/download", path: "/api/v1/folders/folder-owner/download", public: true, want: http.StatusForbidden},
+		{name: "download shared folder of unpublished portfolio", method: http.MethodGet, route: "/api/v1/folders/:id/download", path: "/api/v1/folders/folder-shared/download", public: true, want: http.StatusForbidden},
+		{name: "unknown portfolio site", method: http.MethodGet, route: "/:username", path: "/nobody", public: true, want: http.StatusNotFound},
+		{name: "unknown portfolio site folder", method: http.MethodGet, route: "/:username/folders/:id", path: "/nobody/folders/folder-owner", public: true, want: http.StatusNotFound},
+		{name: "unknown portfolio sitemap", method: http.MethodGet, route: "/:username/sitemap.xml", path: "/nobody/sitemap.xml", public: true, want: http.StatusNotFound},
+		{name: "unknown portfolio atom feed", method: http.MethodGet, route: "/:username/feed.xml", path: "/nobody/feed.xml", public: true, want: http.StatusNotFound},
+		{name: "unknown portfolio rss feed", method: http.MethodGet, route: "/:username/rss.xml", path: "/nobody/rss.xml", public: true, want: http.StatusNotFound},
+		{name: "unknown portfolio site photo", method: http.MethodGet, route: "/:username/photos/:id", path: "/nobody/photos/photo-owner", public: true, want: http.StatusNotFound},
+
+		{name: "own profile", method: http.MethodGet, route: "/api/v1/users/me"},
+		{name: "own usage", method: http.MethodGet, route: "/api/v1/users/me/usage"},
+		{name: "reserved username", method: http.MethodPut, route: "/api/v1/users/me/username", path: "/api/v1/users/me/username", body: `{"username":"admin"}`, want: http.StatusBadRequest},
+
+		{name: "list plans", method: http.MethodGet, route: "/api/v1/membership-plans", path: "/api/v1/membership-plans", want: http.StatusForbidden},
+		{name: "create plan", method: http.MethodPost, route: "/api/v1/membership-plans", path: "/api/v1/membership-plans", body: `{}`, want: http.StatusForbidden},
+		{name: "find plan", method: http.MethodGet, route: "/api/v1/membership-plans/:id", path: "/api/v1/membership-plans/plan", want: http.StatusForbidden},
+		{name: "update plan", method: http.MethodPut, route: "/api/v1/membership-plans/:id", path: "/api/v1/membership-plans/plan", body: `{}`, want: http.StatusForbidden},
+		{name: "delete plan", method: http.MethodDelete, route: "/api/v1/membership-plans/:id", path: "/api/v1/membership-plans/plan", want: http.StatusForbidden},
+
+		{name: "patch foreign portfolio", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"id":"portfolio-owner"}`, want: http.StatusForbidden},
+		{name: "patch foreign profile", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"profiles":{"id":"profile-owner"}}`, want: http.StatusForbidden},
+		{name: "patch foreign folder", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"folders":[{"id":"folder-owner"}]}`, want: http.StatusForbidden},
+		{name: "create folder in foreign portfolio", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"folders":[{"id":"new","portfolio_id":"portfolio-owner"}]}`, want: http.StatusForbidden},
+		{name: "move foreign photo", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"folders":[{"id":"new","photos":[{"public_id":"portfolios/owner/photo.jpg"}]}]}`, want: http.StatusForbidden},
+		{name: "delete foreign folder", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"deleted_folders":["folder-owner"]}`, want: http.StatusForbidden},
+		{name: "delete foreign photo", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"deleted_photos":["portfolios/owner/photo.jpg"]}`, want: http.StatusForbidden},
+		{name: "delete foreign asset", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"deleted_photos":["portfolios/owner/unsaved.jpg"]}`, want: http.StatusForbidden},
+		{name: "own duplicates", method: http.MethodGet, route: "/api/v1/portfolios/duplicates"},
+		{name: "unknown theme", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"theme":"neon"}`, want: http.StatusBadRequest},
+		{name: "unknown theme token", method: http.MethodPatch, route: "/api/v1/portfolios", path: "/api/v1/portfolios", body: `{"theme":"light","theme_settings":{"glow":"#ffffff"}}`, want: http.StatusBadRequest},
+		{name: "themes", method: http.MethodGet, route: "/api/v1/themes"},
+		{name: "own watermark logo", method: http.MethodPost, route: "/api/v1/portfolios/watermark/logo"},
+
+		{name: "import into foreign folder", method: http.MethodPost, route: "/api/v1/folders/:id/import-zip", path: "/api/v1/folders/folder-owner/import-zip", want: http.StatusForbidden},
+		{name: "foreign import job", method: http.MethodGet, route: "/api/v1/folders/:id/import-zip/:jobId", path: "/api/v1/folders/folder-owner/import-zip/job", want: http.StatusForbidden},
+
+		{name: "replace foreign photo file", method: http.MethodPut, route: "/api/v1/photos/:id/file", path: "/api/v1/photos/photo-owner/file", form: map[string]string{}, want: http.StatusForbidden},
+
+		{name: "own domains", method: http.MethodGet, route: "/api/v1/domains"},
+		{name: "invalid domain", method: http.MethodPost, route: "/api/v1/domains", path: "/api/v1/domains", body: `{"hostname":"localhost"}`, want: http.StatusBadRequest},
+		{name: "verify foreign domain", method: http.MethodPost, route: "/api/v1/domains/:id/verify", path: "/api/v1/domains/domain-owner/verify", want: http.StatusForbidden},
+		{name: "delete foreign domain", method: http.MethodDelete, route: "/api/v1/domains/:id", path: "/api/v1/domains/domain-owner", want: http.StatusForbidden},
+
+		{name: "reconcile", method: http.MethodPost, route: "/api/v1/admin/reconcile", path: "/api/v1/admin/reconcile", want: http.StatusForbidden},
+		{name: "asset deletion stats", method: http.MethodGet, route: "/api/v1/admin/asset-deletions", path: "/api/v1/admin/asset-deletions", want: http.StatusForbidden},
+		{name: "retry asset deletions", method: http.MethodPost, route: "/api/v1/admin/asset-deletions/retry", path: "/api/v1/admin/asset-deletions/retry", want: http.StatusForbidden},
+		{name: "metrics", method: http.MethodGet, route: "/api/v1/admin/metrics", path: "/api/v1/admin/metrics", want: http.StatusForbidden},
+
+		{name: "upload into foreign folder", method: http.MethodPost, route: "/api/v1/uploads", path: "/api/v1/uploads", form: map[string]string{"folder_id": "folder-owner"}, want: http.StatusForbidden},
+		{name: "upload over foreign photo", method: http.MethodPost, route: "/api/v1/uploads", path: "/api/v1/uploads", form: map[string]string{"id": "photo-owner"}, want: http.StatusForbidden},
+		{name: "remove foreign assets", method: http.MethodDelete, route: "/api/v1/uploads", path: "/api/v1/uploads", body: `{"public_ids":["portfolios/owner/photo.jpg"]}`, want: http.StatusForbidden},
+		{name: "purge foreign uploads", method: http.MethodPost, route: "/api/v1/uploads/purge", path: "/api/v1/uploads/purge", body: `{"user_id":"owner","dry_run":true}`, want: http.StatusForbidden},
+		{name: "own signed upload", method: http.MethodPost, route: "/api/v1/uploads/sign"},
+		{name: "confirm foreign asset", method: http.MethodPost, route: "/api/v1/uploads/confirm", path: "/api/v1/uploads/confirm", body: `{"public_id":"portfolios/owner/photo.jpg"}`, want: http.StatusForbidden},
+		{name: "confirm into foreign folder", method: http.MethodPost, route: "/api/v1/uploads/confirm", path: "/api/v1/uploads/confirm", body: `{"public_id":"portfolios/intruder/photo.jpg","folder_id":"folder-owner"}`, want: http.StatusForbidden},
+		{name: "confirm over foreign photo", method: http.MethodPost, route: "/api/v1/uploads/confirm", path: "/api/v1/uploads/confirm", body: `{"public_id":"portfolios/intruder/photo.jpg","id":"photo-owner"}`, want: http.StatusForbidden},
+		{name: "tus options", method: http.MethodOptions, route: "/api/v1/uploads/tus", path: "/api/v1/uploads/tus", want: http.StatusNoContent},
+		{
+			name:   "tus upload into foreign folder",
+			method: http.MethodPost,
+			route:  "/api/v1/uploads/tus",
+			path:   "/api/v1/uploads/tus",
+			header: map[string]string{
+				"Tus-Resumable":   model.TusVersion,
+				"Upload-Length":   "10",
+				"Upload-Metadata": "folder_id " + base64.StdEncoding.EncodeToString([]byte("folder-owner")),
+			},
+			want: http.StatusForbidden,
+		},
+		{name: "tus head foreign upload", method: http.MethodHead, route: "/api/v1/uploads/tus/:id", path: "/api/v1/uploads/tus/upload-owner", header: tusHeader, want: http.StatusForbidden},
+		{name: "tus patch foreign upload", method: http.MethodPatch, route: "/api/v1/uploads/tus/:id", path: "/api/v1/uploads/tus/upload-owner", header: tusHeader, want: http.StatusForbidden},
+		{name: "tus delete foreign upload", method: http.MethodDelete, route: "/api/v1/uploads/tus/:id", path: "/api/v1/uploads/tus/upload-owner", header: tusHeader, want: http.StatusForbidden},
+	}
+}
+
+func TestRouteCasesCoverRouter(t *testing.T) {
+	e, _ := newTestService()
+
+	covered := map[string]bool{}
+	for _, tc := range routeCases() {
+		covered[tc.method+" "+tc.route] = true
+	}
+
+	registered := map[string]bool{}
+
+	for _, r := range e.Routes() {
+		if r.Method == echo.RouteNotFound {
+			continue
+		}
+
+		key := r.Method + " " + r.Path
+		registered[key] = true
+
+		if !covered[key] {
+			t.Errorf("route %s has no authorization case", key)
+		}
+	}
+
+	for key := range covered {
+		if !registered[key] {
+			t.Errorf("case for %s matches no registered route", key)
+		}
+	}
+}
+
+func TestRoutesRejectCrossTenantAccess(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test")
+	t.Setenv("UPLOADER_BASE_PATH", "")
+
+	e, _ := newTestService()
+	token := testToken(t, intruderID, model.RoleUser)
+
+	for _, tc := range routeCases() {
+		if tc.want == 0 {
+			continue
+		}
+
+		t.Run(tc.name, func(t *testing.T) {
+			var body io.Reader = bytes.NewBufferString(tc.body)
+			contentType := echo.MIMEApplicationJSON
+
+			if tc.form != nil {
+				body, contentType = multipartBody(t, tc.form)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			req.Header.Set(echo.HeaderContentType, contentType)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("%s %s: got status %d, want %d: %s", tc.method, tc.path, rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoutesRequireToken(t *testing.T) {
+	e, _ := newTestService()
+
+	for _, tc := range routeCases() {
+		if tc.public {
+			continue
+		}
+
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, routePath(tc), nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s: got status %d, want %d", tc.method, tc.route, rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	_, h := newTestService()
+
+	tests := []struct {
+		name    string
+		session jwtClaims
+		ids     []string
+		want    int
+	}{
+		{name: "owner", session: jwtClaims{ID: ownerID, Role: model.RoleUser}, ids: []string{"folder-owner"}},
+		{name: "unknown id", session: jwtClaims{ID: intruderID, Role: model.RoleUser}, ids: []string{"folder-new"}},
+		{name: "empty id", session: jwtClaims{ID: intruderID, Role: model.RoleUser}, ids: []string{""}},
+		{name: "intruder", session: jwtClaims{ID: intruderID, Role: model.RoleUser}, ids: []string{"folder-new", "folder-owner"}, want: http.StatusForbidden},
+		{name: "admin", session: jwtClaims{ID: intruderID, Role: model.RoleAdmin}, ids: []string{"folder-owner"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := h.authorize(context.Background(), tt.session, model.ResourceFolder, tt.ids...)
+			if status != tt.want {
+				t.Errorf("got status %d (%v), want %d", status, err, tt.want)
+			}
+		})
+	}
+}
+
+// routePath falls back to the route pattern for cases without parameters.
+func routePath(tc routeCase) string {
+	if tc.path != "" {
+		return tc.path
+	}
+
+	return tc.route
+}
@@ -0,0 +1,147 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// customDomainCacheSize caps how many resolved hosts are kept in memory.
+const customDomainCacheSize = 10000
+
+// CustomDomainMiddleware serves public portfolios on verified custom domains
+// by rewriting the request path to the owner's portfolio. Resolved hosts are
+// cached for ttl, so a removed or deactivated domain stops resolving within
+// it. Unknown hosts are not cached, so arbitrary Host headers cannot fill
+// the cache.
+type CustomDomainMiddleware struct {
+	repo     model.CustomDomainRepository
+	appHosts map[string]bool
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]customDomainHost
+}
+
+type customDomainHost struct {
+	username  string
+	expiresAt time.Time
+}
+
+// NewCustomDomainMiddleware resolves every host except appHosts, the ones the
+// API itself is served on.
+func NewCustomDomainMiddleware(repo model.CustomDomainRepository, appHosts []string, ttl time.Duration) *CustomDomainMiddleware {
+	hosts := map[string]bool{}
+	for _, host := range appHosts {
+		hosts[model.NormalizeHostname(host)] = true
+	}
+
+	return &CustomDomainMiddleware{
+		repo:     repo,
+		appHosts: hosts,
+		ttl:      ttl,
+		hosts:    map[string]customDomainHost{},
+	}
+}
+
+// Resolve must run through Echo#Pre so the rewritten path gets routed.
+func (m *CustomDomainMiddleware) Resolve(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		host := model.NormalizeHostname(req.Host)
+
+		if m.appHosts[host] {
+			c.Set("app_host", host)
+			return next(c)
+		}
+
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return next(c)
+		}
+
+		username, err := m.username(c, host)
+		if err != nil {
+			logrus.WithField("host", host).WithError(err).Error("failed to resolve custom domain")
+			return next(c)
+		}
+
+		if username == "" {
+			return next(c)
+		}
+
+		if path, ok := customDomainPath(req.URL.Path, username); ok {
+			req.URL.Path = path
+			req.URL.RawPath = ""
+			c.Set("custom_domain", host)
+		}
+
+		return next(c)
+	}
+}
+
+// customDomainPath maps a path on a custom domain to the route serving it:
+// the portfolio site lives at the root and its JSON under a fixed path.
+// Other paths are left alone.
+func customDomainPath(path, username string) (string, bool) {
+	switch {
+	case path == "/api/v1/public/portfolio":
+		return "/api/v1/public/portfolios/" + username, true
+	case path == "/":
+		return "/" + username, true
+	case path == "/sitemap.xml", path == "/feed.xml", path == "/rss.xml",
+		strings.HasPrefix(path, "/folders/"), strings.HasPrefix(path, "/photos/"):
+		return "/" + username + path, true
+	}
+
+	return "", false
+}
+
+// username returns the username behind host, or an empty string when host
+// is not a verified custom domain.
+func (m *CustomDomainMiddleware) username(c echo.Context, host string) (string, error) {
+	m.mu.Lock()
+	cached, ok := m.hosts[host]
+	m.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.username, nil
+	}
+
+	username, err := m.repo.FindUsernameByHost(c.Request().Context(), host)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if username != "" {
+		m.cache(host, username)
+	}
+
+	return username, nil
+}
+
+// cache stores a resolved host, evicting expired entries first. When the
+// cache is still full the host is looked up again next time.
+func (m *CustomDomainMiddleware) cache(host, username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for h, cached := range m.hosts {
+		if now.After(cached.expiresAt) {
+			delete(m.hosts, h)
+		}
+	}
+
+	if len(m.hosts) >= customDomainCacheSize {
+		return
+	}
+
+	m.hosts[host] = customDomainHost{username: username, expiresAt: now.Add(m.ttl)}
+}
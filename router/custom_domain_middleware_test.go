@@ -0,0 +1,117 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"gorm.io/gorm"
+)
+
+// fakeCustomDomainRepository only implements FindUsernameByHost and counts
+// lookups; other methods panic.
+type fakeCustomDomainRepository struct {
+	model.CustomDomainRepository
+	usernames map[string]string
+	lookups   int
+}
+
+func (f *fakeCustomDomainRepository) FindUsernameByHost(ctx context.Context, host string) (string, error) {
+	f.lookups++
+
+	username, ok := f.usernames[host]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return username, nil
+}
+
+func TestCustomDomainMiddleware(t *testing.T) {
+	repo := &fakeCustomDomainRepository{usernames: map[string]string{"photos.example.com": "jane"}}
+	middleware := NewCustomDomainMiddleware(repo, []string{"api.ekspresi.test"}, time.Minute)
+
+	e := echo.New()
+	e.Pre(middleware.Resolve)
+	e.GET("/api/v1/public/portfolios/:username", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("username"))
+	})
+	e.GET("/:username", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("username"))
+	})
+	e.GET("/:username/photos/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("username")+"/"+c.Param("id"))
+	})
+
+	tests := []struct {
+		name   string
+		host   string
+		path   string
+		method string
+		want   int
+		body   string
+	}{
+		{name: "verified domain", host: "photos.example.com", method: http.MethodGet, want: http.StatusOK, body: "jane"},
+		{name: "verified domain with port", host: "Photos.Example.com:443", method: http.MethodGet, want: http.StatusOK, body: "jane"},
+		{name: "unknown domain", host: "other.example.com", method: http.MethodGet, want: http.StatusNotFound},
+		{name: "app host", host: "api.ekspresi.test", method: http.MethodGet, want: http.StatusNotFound},
+		{name: "write request", host: "photos.example.com", method: http.MethodPost, want: http.StatusNotFound},
+		{name: "site home", host: "photos.example.com", path: "/", method: http.MethodGet, want: http.StatusOK, body: "jane"},
+		{name: "site photo", host: "photos.example.com", path: "/photos/p1", method: http.MethodGet, want: http.StatusOK, body: "jane/p1"},
+		{name: "site on app host", host: "api.ekspresi.test", path: "/photos/p1", method: http.MethodGet, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/api/v1/public/portfolio"
+			}
+
+			req := httptest.NewRequest(tt.method, path, nil)
+			req.Host = tt.host
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tt.body)
+			}
+		})
+	}
+
+	if repo.lookups != 2 {
+		t.Errorf("got %d lookups, want 2 with hosts cached", repo.lookups)
+	}
+}
+
+func TestCustomDomainMiddlewareSkipsUnknownHosts(t *testing.T) {
+	repo := &fakeCustomDomainRepository{usernames: map[string]string{"photos.example.com": "jane"}}
+	middleware := NewCustomDomainMiddleware(repo, nil, time.Minute)
+
+	e := echo.New()
+	e.Pre(middleware.Resolve)
+
+	for _, host := range []string{"a.example.com", "b.example.com", "a.example.com", "photos.example.com", "photos.example.com"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if repo.lookups != 4 {
+		t.Errorf("got %d lookups, want 4 with only the verified host cached", repo.lookups)
+	}
+
+	if len(middleware.hosts) != 1 {
+		t.Errorf("got %d cached hosts, want 1", len(middleware.hosts))
+	}
+}
@@ -0,0 +1,151 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (h *httpService) findDomainsHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	domains, err := h.customDomainRepo.FindByUserID(c.Request().Context(), session.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: domains})
+}
+
+// createDomainHandler registers a hostname and returns the TXT record that
+// proves ownership of it.
+func (h *httpService) createDomainHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	var input model.CustomDomainInput
+
+	if err := c.Bind(&input); err != nil {
+		logger.WithError(err).Error("failed to bind request")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	hostname := model.NormalizeHostname(input.Hostname)
+
+	if err := model.ValidateHostname(hostname); err != nil {
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	if status, err := h.checkCustomDomainPlan(c, session.ID); err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	domain := model.NewCustomDomain(session.ID, hostname)
+
+	err = h.customDomainRepo.Create(c.Request().Context(), domain)
+	if errors.Is(err, model.ErrDomainTaken) {
+		return c.JSON(http.StatusConflict, response{Message: err.Error()})
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, response{Success: true, Data: domain})
+}
+
+// verifyDomainHandler checks the TXT record now instead of waiting for the
+// verification worker.
+func (h *httpService) verifyDomainHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	domain, status, err := h.findOwnedDomain(c, session)
+	if err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	if status, err := h.checkCustomDomainPlan(c, domain.UserID); err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	domain, err = h.domainVerifier.Verify(c.Request().Context(), domain)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: domain.Status == model.DomainStatusVerified, Message: domain.FailureReason, Data: domain})
+}
+
+func (h *httpService) deleteDomainHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	domain, status, err := h.findOwnedDomain(c, session)
+	if err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	if err := h.customDomainRepo.Delete(c.Request().Context(), domain.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true})
+}
+
+func (h *httpService) findOwnedDomain(c echo.Context, session jwtClaims) (model.CustomDomain, int, error) {
+	if status, err := h.authorize(c.Request().Context(), session, model.ResourceDomain, c.Param("id")); err != nil {
+		return model.CustomDomain{}, status, err
+	}
+
+	domain, err := h.customDomainRepo.FindByID(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.CustomDomain{}, http.StatusNotFound, errors.New("domain not found")
+	}
+
+	if err != nil {
+		return model.CustomDomain{}, http.StatusInternalServerError, err
+	}
+
+	return domain, 0, nil
+}
+
+// checkCustomDomainPlan rejects users whose plan does not include custom
+// domains.
+func (h *httpService) checkCustomDomainPlan(c echo.Context, userID string) (int, error) {
+	plan, err := h.activePlan(c.Request().Context(), userID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if !plan.CustomDomain {
+		return http.StatusForbidden, model.ErrCustomDomainNotAllowed
+	}
+
+	return 0, nil
+}
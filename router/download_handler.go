@@ -0,0 +1,224 @@
+package router
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type downloadEntry struct {
+	name     string
+	publicID string
+	photo    model.Photo
+}
+
+// downloadFolderHandler streams a folder as a ZIP archive with a
+// captions.csv manifest that importZipHandler reads back. Entries are copied
+// from storage one at a time, so the archive is never held in memory. Since
+// its bytes are only known once written, ranges are not supported.
+func (h *httpService) downloadFolderHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := logrus.WithContext(ctx)
+
+	folder, err := h.portfolioRepo.FindFolderByID(ctx, c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, response{Message: "folder not found"})
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("failed to find folder")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	owner := false
+
+	if session, err := authSession(c); err == nil {
+		status, err := h.authorize(ctx, session, model.ResourceFolder, folder.ID)
+		if status == http.StatusInternalServerError {
+			logger.WithError(err).Error("failed to authorize folder download")
+			return c.JSON(status, response{Message: err.Error()})
+		}
+
+		owner = status == 0
+	}
+
+	if !owner && !folder.DownloadAllowed() {
+		return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+	}
+
+	// Visitors only reach folders of published portfolios.
+	if !owner {
+		portfolio, err := h.portfolioRepo.FindByID(ctx, folder.PortfolioID)
+		if err != nil {
+			logger.WithError(err).Error("failed to find folder portfolio")
+			return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+		}
+
+		if portfolio.Published == nil || !*portfolio.Published {
+			return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+		}
+	}
+
+	photos, err := h.uploaderRepo.FindByFolderID(ctx, folder.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to find folder photos")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	entries := downloadEntries(photos, c.QueryParam("variant"), owner)
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "application/zip")
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": archiveName(folder.Name)}))
+	header.Set("Cache-Control", "private, no-store")
+	header.Set("Accept-Ranges", "none")
+	header.Set("X-Archive-Entries", strconv.Itoa(len(entries)))
+	c.Response().WriteHeader(http.StatusOK)
+
+	archive := zip.NewWriter(c.Response())
+
+	manifest := [][]string{{"filename", "caption", "alt"}}
+
+	for _, entry := range entries {
+		logger := logger.WithField("public_id", entry.publicID)
+
+		err := h.writeDownloadEntry(ctx, archive, entry)
+		if errors.Is(err, model.ErrAssetNotFound) {
+			logger.Warn("skipped missing asset in folder download")
+			continue
+		}
+
+		// The status is already sent, so a broken archive is all the client
+		// gets to see.
+		if err != nil {
+			logger.WithError(err).Error("failed to write folder download")
+			return nil
+		}
+
+		manifest = append(manifest, []string{entry.name, entry.photo.Caption, entry.photo.Alt})
+	}
+
+	w, err := archive.Create(zipCaptionsFile)
+	if err != nil {
+		logger.WithError(err).Error("failed to write download manifest")
+		return nil
+	}
+
+	if err := csv.NewWriter(w).WriteAll(manifest); err != nil {
+		logger.WithError(err).Error("failed to write download manifest")
+		return nil
+	}
+
+	if err := archive.Close(); err != nil {
+		logger.WithError(err).Error("failed to finish folder download")
+	}
+
+	return nil
+}
+
+// writeDownloadEntry copies one asset into the archive. Media is already
+// compressed, so it is stored as is. Opening happens before the entry is
+// created to let a missing asset be skipped.
+func (h *httpService) writeDownloadEntry(ctx context.Context, archive *zip.Writer, entry downloadEntry) error {
+	src, err := h.uploaderRepo.Open(ctx, entry.publicID)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     entry.name,
+		Method:   zip.Store,
+		Modified: entry.photo.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, src)
+
+	return err
+}
+
+// downloadEntries names the asset of every photo in the archive. variant
+// picks a rendition by name; photos without it, such as videos, fall back to
+// what the caller may see by default: the original for owners and the
+// delivered source for visitors.
+func downloadEntries(photos []model.Photo, variant string, owner bool) []downloadEntry {
+	var entries []downloadEntry
+
+	for _, photo := range photos {
+		publicID, src := downloadSource(photo, variant, owner)
+		if publicID == "" {
+			continue
+		}
+
+		entries = append(entries, downloadEntry{
+			name:     fmt.Sprintf("%03d-%s%s", len(entries)+1, photo.ID, srcExt(src)),
+			publicID: publicID,
+			photo:    photo,
+		})
+	}
+
+	return entries
+}
+
+// downloadSource returns the public ID and URL of the asset to archive.
+// Visitors never get the private original of a watermarked photo.
+func downloadSource(photo model.Photo, variant string, owner bool) (string, string) {
+	if variant != "" {
+		for _, v := range photo.Variants {
+			if v.Name == variant {
+				return v.PublicID, v.Src
+			}
+		}
+	}
+
+	if photo.OriginalSrc == "" || photo.Src == photo.OriginalSrc {
+		return photo.PublicID, photo.Src
+	}
+
+	if owner {
+		return photo.PublicID, photo.OriginalSrc
+	}
+
+	for _, v := range photo.Variants {
+		if v.Src == photo.Src {
+			return v.PublicID, v.Src
+		}
+	}
+
+	return "", ""
+}
+
+func srcExt(src string) string {
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(path.Ext(u.Path))
+}
+
+func archiveName(folderName string) string {
+	name := strings.TrimSpace(folderName)
+	if name == "" {
+		name = "folder"
+	}
+
+	return name + ".zip"
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *httpService) duplicatesHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	photos, err := h.uploaderRepo.FindHashedByUserID(c.Request().Context(), session.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to find photos")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{
+		Success: true,
+		Data:    groupDuplicates(photos, h.duplicateThreshold),
+	})
+}
+
+// findDuplicates returns the IDs of the user's photos within the duplicate
+// threshold of hash, other than photoID itself.
+func (h *httpService) findDuplicates(ctx context.Context, userID, photoID, hash string) ([]string, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	photos, err := h.uploaderRepo.FindHashedByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+
+	for _, p := range photos {
+		if p.ID == photoID {
+			continue
+		}
+
+		if d := imaging.HashDistance(hash, p.PHash); d >= 0 && d <= h.duplicateThreshold {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// groupDuplicates clusters photos whose hashes are within threshold of each
+// other, directly or through a chain of near matches. Photos without a match
+// are left out.
+func groupDuplicates(photos []model.Photo, threshold int) [][]model.Photo {
+	parent := make([]int, len(photos))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+
+		return parent[i]
+	}
+
+	for i := range photos {
+		for j := i + 1; j < len(photos); j++ {
+			if d := imaging.HashDistance(photos[i].PHash, photos[j].PHash); d >= 0 && d <= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := map[int][]model.Photo{}
+	var roots []int
+
+	for i, p := range photos {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+
+		members[root] = append(members[root], p)
+	}
+
+	groups := [][]model.Photo{}
+
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, members[root])
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"sync"
+	"time"
+)
+
+// feedCacheTTL bounds how long a document survives changes that do not
+// invalidate it.
+const feedCacheTTL = time.Hour
+
+// feedCacheSize caps how many documents are kept in memory.
+const feedCacheSize = 10000
+
+// feedCache keeps rendered sitemaps and feeds in memory. Entries remember
+// whose portfolio they were built from, so a patch or an upload drops every
+// document of that portfolio whatever host or username it was served on.
+type feedCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]feedCacheEntry
+}
+
+type feedCacheEntry struct {
+	userID      string
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+func newFeedCache(ttl time.Duration) *feedCache {
+	return &feedCache{
+		ttl:     ttl,
+		entries: map[string]feedCacheEntry{},
+	}
+}
+
+func (fc *feedCache) get(key string) (feedCacheEntry, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	entry, ok := fc.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return feedCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// set stores a document, evicting expired entries first. When the cache is
+// still full the document is rendered again next time.
+func (fc *feedCache) set(key string, entry feedCacheEntry) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range fc.entries {
+		if now.After(e.expiresAt) {
+			delete(fc.entries, k)
+		}
+	}
+
+	if _, ok := fc.entries[key]; !ok && len(fc.entries) >= feedCacheSize {
+		return
+	}
+
+	entry.expiresAt = now.Add(fc.ttl)
+	fc.entries[key] = entry
+}
+
+// InvalidateFeeds drops the cached documents of a user's portfolio, for
+// changes made outside of a request such as re-rendered variants.
+func (h *httpService) InvalidateFeeds(userID string) {
+	h.feedCache.invalidateUser(userID)
+}
+
+// invalidateUser drops the documents of a user's portfolio.
+func (fc *feedCache) invalidateUser(userID string) {
+	fc.invalidate(func(e feedCacheEntry) bool { return e.userID == userID })
+}
+
+func (fc *feedCache) invalidate(match func(feedCacheEntry) bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	for k, e := range fc.entries {
+		if match(e) {
+			delete(fc.entries, k)
+		}
+	}
+}
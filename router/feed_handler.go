@@ -0,0 +1,391 @@
+package router
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// feedEntries caps the number of recent photos and folders in a feed.
+const feedEntries = 50
+
+var (
+	feedFolderTemplate = template.Must(template.New("folder").Parse(
+		`{{with .Cover}}<p><a href="{{.URL}}"><img src="{{.Src}}" alt="{{.Alt}}"></a></p>{{end}}{{with .Description}}<p>{{.}}</p>{{end}}<p>{{len .Photos}} photos</p>`))
+	feedPhotoTemplate = template.Must(template.New("photo").Parse(
+		`<p><a href="{{.URL}}"><img src="{{.Src}}" alt="{{.Alt}}"{{if .Width}} width="{{.Width}}" height="{{.Height}}"{{end}}></a></p>{{with .Caption}}<p>{{.}}</p>{{end}}`))
+)
+
+type sitemapURLSet struct {
+	XMLName  xml.Name     `xml:"urlset"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	XMLNSImg string       `xml:"xmlns:image,attr"`
+	URLs     []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string         `xml:"loc"`
+	LastMod string         `xml:"lastmod,omitempty"`
+	Images  []sitemapImage `xml:"image:image"`
+}
+
+type sitemapImage struct {
+	Loc string `xml:"image:loc"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Link      atomLink `xml:"link"`
+	Content   atomHTML `xml:"content"`
+}
+
+type atomHTML struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	XMLNS   string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// feedItem is a recently added photo or folder, shared by both feed formats.
+type feedItem struct {
+	title     string
+	url       string
+	published time.Time
+	html      string
+}
+
+// feedDocument renders a document of a published portfolio.
+type feedDocument func(s site, page sitePage, portfolio model.PublicPortfolio) (interface{}, error)
+
+// sitemapHandler lists every page of a published portfolio, with the image
+// of each photo page, for search engines.
+func (h *httpService) sitemapHandler(c echo.Context) error {
+	return h.serveFeed(c, "/sitemap.xml", "application/xml; charset=utf-8", renderSitemap)
+}
+
+// atomFeedHandler serves the recently added photos and folders as Atom.
+func (h *httpService) atomFeedHandler(c echo.Context) error {
+	return h.serveFeed(c, "/feed.xml", "application/atom+xml; charset=utf-8", renderAtomFeed)
+}
+
+// rssFeedHandler serves the recently added photos and folders as RSS 2.0.
+func (h *httpService) rssFeedHandler(c echo.Context) error {
+	return h.serveFeed(c, "/rss.xml", "application/rss+xml; charset=utf-8", renderRSSFeed)
+}
+
+// serveFeed answers from the feed cache, or renders the document and caches
+// it. Documents hold absolute URLs, so they are cached per host.
+func (h *httpService) serveFeed(c echo.Context, suffix, contentType string, render feedDocument) error {
+	req := c.Request()
+	key, cacheable := feedCacheKey(c, suffix)
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=60")
+
+	if entry, ok := h.feedCache.get(key); cacheable && ok {
+		return c.Blob(http.StatusOK, entry.contentType, entry.body)
+	}
+
+	pt, ok, err := h.findSitePortfolio(c, suffix)
+	if !ok {
+		c.Response().Header().Del("Cache-Control")
+		return err
+	}
+
+	portfolio := model.NewPublicPortfolio(model.NormalizeUsername(c.Param("username")), pt)
+	s := newSite(c, portfolio.Username)
+
+	doc, err := render(s, newSitePage(s, portfolio), portfolio)
+	if err != nil {
+		logrus.WithContext(req.Context()).WithError(err).WithField("path", req.URL.Path).Error("failed to render feed")
+		return c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	body, err := xml.Marshal(doc)
+	if err != nil {
+		logrus.WithContext(req.Context()).WithError(err).WithField("path", req.URL.Path).Error("failed to encode feed")
+		return c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	body = append([]byte(xml.Header), body...)
+
+	if cacheable {
+		h.feedCache.set(key, feedCacheEntry{
+			userID:      pt.UserID,
+			contentType: contentType,
+			body:        body,
+		})
+	}
+
+	return c.Blob(http.StatusOK, contentType, body)
+}
+
+// feedCacheKey keys a document on the host the custom domain middleware
+// resolved, a custom domain or an app host, and the normalized username.
+// Documents served on any other host are not cached, so neither Host headers
+// nor username casing can grow the cache.
+func feedCacheKey(c echo.Context, suffix string) (string, bool) {
+	host, ok := c.Get("custom_domain").(string)
+	if !ok {
+		if host, ok = c.Get("app_host").(string); !ok {
+			return "", false
+		}
+	}
+
+	return c.Scheme() + "://" + host + "/" + model.NormalizeUsername(c.Param("username")) + suffix, true
+}
+
+func renderSitemap(s site, page sitePage, portfolio model.PublicPortfolio) (interface{}, error) {
+	urlset := sitemapURLSet{
+		XMLNS:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		XMLNSImg: "http://www.google.com/schemas/sitemap-image/1.1",
+		URLs: []sitemapURL{{
+			Loc:     s.url(""),
+			LastMod: sitemapDate(portfolio.UpdatedAt),
+		}},
+	}
+
+	for _, f := range portfolio.Folders {
+		folder := newSiteFolder(s, portfolio, f)
+		folderURL := sitemapURL{Loc: folder.URL}
+		var photoURLs []sitemapURL
+
+		lastMod := folder.CreatedAt
+
+		for _, photo := range folder.Photos {
+			photoURL := sitemapURL{Loc: photo.URL, LastMod: sitemapDate(photo.CreatedAt)}
+
+			if image := newSiteImage(s, photo); image != nil {
+				folderURL.Images = append(folderURL.Images, sitemapImage{Loc: image.Src})
+				photoURL.Images = []sitemapImage{{Loc: image.Src}}
+			}
+
+			if photo.CreatedAt.After(lastMod) {
+				lastMod = photo.CreatedAt
+			}
+
+			photoURLs = append(photoURLs, photoURL)
+		}
+
+		folderURL.LastMod = sitemapDate(lastMod)
+
+		urlset.URLs = append(urlset.URLs, folderURL)
+		urlset.URLs = append(urlset.URLs, photoURLs...)
+	}
+
+	return urlset, nil
+}
+
+func renderAtomFeed(s site, page sitePage, portfolio model.PublicPortfolio) (interface{}, error) {
+	items, err := feedItems(s, portfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		ID:      s.url(""),
+		Title:   page.SiteName,
+		Updated: feedUpdated(portfolio, items).Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: s.url("/feed.xml")},
+			{Rel: "alternate", Type: "text/html", Href: s.url("")},
+		},
+		Author: atomAuthor{Name: page.SiteName, URI: s.url("")},
+	}
+
+	for _, item := range items {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        item.url,
+			Title:     item.title,
+			Published: item.published.Format(time.RFC3339),
+			Updated:   item.published.Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: item.url},
+			Content:   atomHTML{Type: "html", Body: item.html},
+		})
+	}
+
+	return feed, nil
+}
+
+func renderRSSFeed(s site, page sitePage, portfolio model.PublicPortfolio) (interface{}, error) {
+	items, err := feedItems(s, portfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         page.SiteName,
+			Link:          s.url(""),
+			Description:   summary(firstNonEmpty(portfolio.Profiles.Bio, portfolio.Description, "Photography portfolio of "+page.SiteName), siteDescriptionLength),
+			LastBuildDate: feedUpdated(portfolio, items).Format(time.RFC1123Z),
+			Self:          atomLink{Rel: "self", Type: "application/rss+xml", Href: s.url("/rss.xml")},
+		},
+	}
+
+	for _, item := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.title,
+			Link:        item.url,
+			GUID:        rssGUID{IsPermaLink: true, ID: item.url},
+			PubDate:     item.published.Format(time.RFC1123Z),
+			Description: item.html,
+		})
+	}
+
+	return feed, nil
+}
+
+// feedItems returns the newest photos and folders of a portfolio, newest
+// first. Entry bodies are HTML, escaped again by the XML encoder.
+func feedItems(s site, portfolio model.PublicPortfolio) ([]feedItem, error) {
+	var items []feedItem
+
+	for _, f := range portfolio.Folders {
+		folder := newSiteFolder(s, portfolio, f)
+		if folder.Cover != nil {
+			cover := feedPhoto(s, *folder.Cover)
+			folder.Cover = &cover
+		}
+
+		body, err := feedHTML(feedFolderTemplate, folder)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, feedItem{
+			title:     folder.Name,
+			url:       folder.URL,
+			published: folder.CreatedAt,
+			html:      body,
+		})
+
+		for _, photo := range folder.Photos {
+			body, err := feedHTML(feedPhotoTemplate, feedPhoto(s, photo))
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, feedItem{
+				title:     summary(firstNonEmpty(photo.Caption, photo.Alt, "New in "+folder.Name), 80),
+				url:       photo.URL,
+				published: photo.CreatedAt,
+				html:      body,
+			})
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b feedItem) int {
+		return b.published.Compare(a.published)
+	})
+
+	if len(items) > feedEntries {
+		items = items[:feedEntries]
+	}
+
+	return items, nil
+}
+
+// feedHTML renders an entry body.
+func feedHTML(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render feed entry: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// feedPhoto makes the image of a photo absolute, since feed readers show
+// entries away from the site. Videos show their poster.
+func feedPhoto(s site, photo sitePhoto) sitePhoto {
+	if photo.MediaType == model.MediaTypeVideo {
+		photo.Src = photo.PosterSrc
+	}
+
+	photo.Src = s.absolute(photo.Src)
+
+	return photo
+}
+
+// feedUpdated is the time of the newest entry, or of the last portfolio
+// change when that is later.
+func feedUpdated(portfolio model.PublicPortfolio, items []feedItem) time.Time {
+	updated := portfolio.UpdatedAt
+
+	if len(items) > 0 && items[0].published.After(updated) {
+		updated = items[0].published
+	}
+
+	return updated.UTC()
+}
+
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format("2006-01-02")
+}
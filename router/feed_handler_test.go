@@ -0,0 +1,188 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+)
+
+// fakeUploaderRepository finds no photo rows and deletes nothing; other
+// methods panic.
+type fakeUploaderRepository struct {
+	model.UploaderRepository
+}
+
+func (f fakeUploaderRepository) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]model.Photo, error) {
+	return nil, nil
+}
+
+func (f fakeUploaderRepository) DeleteByPublicIDs(ctx context.Context, publicIDs []string) error {
+	return nil
+}
+
+// fakeUploadPurger purges nothing.
+type fakeUploadPurger struct {
+	model.UploadPurger
+}
+
+func (f fakeUploadPurger) Purge(ctx context.Context, actorID, prefix string) (model.PurgeReport, error) {
+	return model.PurgeReport{Prefix: prefix}, nil
+}
+
+// newFeedTestService serves the owner's portfolio, published as jane.
+func newFeedTestService() (*echo.Echo, *fakePortfolioRepository) {
+	added := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+
+	e, h := newTestService()
+	e.Pre(NewCustomDomainMiddleware(&fakeCustomDomainRepository{}, []string{"example.com"}, time.Minute).Resolve)
+	h.RegisterUploaderRepository(fakeUploaderRepository{})
+	h.RegisterUploadPurger(fakeUploadPurger{})
+
+	repo := h.portfolioRepo.(*fakePortfolioRepository)
+	repo.published = map[string]model.PortfolioType{"jane": {
+		Portfolio: model.Portfolio{ID: "portfolio-owner", UserID: ownerID, UpdatedAt: added},
+		Profiles:  model.Profile{Name: "Jane"},
+		Folders: []model.FolderType{{
+			Folder: model.Folder{ID: "bali", Name: "Bali", CreatedAt: added.Add(-time.Hour)},
+			Photos: []model.Photo{
+				{ID: "sunset", Src: "/files/sunset.webp", Caption: "Sunset & sea", MediaType: model.MediaTypeImage, CreatedAt: added},
+				{ID: "waves", Src: "/files/waves.mp4", PosterSrc: "/files/waves.jpg", MediaType: model.MediaTypeVideo, CreatedAt: added.Add(-time.Minute)},
+			},
+		}},
+	}}
+
+	return e, repo
+}
+
+func TestFeeds(t *testing.T) {
+	e, _ := newFeedTestService()
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    []string
+	}{
+		{
+			path:        "/jane/sitemap.xml",
+			contentType: "application/xml",
+			contains: []string{
+				`xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"`,
+				"<loc>http://example.com/jane/photos/sunset</loc><lastmod>2025-04-20</lastmod><image:image><image:loc>http://example.com/files/sunset.webp</image:loc></image:image>",
+				"<image:loc>http://example.com/files/waves.jpg</image:loc>",
+			},
+		},
+		{
+			path:        "/jane/feed.xml",
+			contentType: "application/atom+xml",
+			contains: []string{
+				`<link rel="self" type="application/atom+xml" href="http://example.com/jane/feed.xml"></link>`,
+				"<entry><id>http://example.com/jane/photos/sunset</id><title>Sunset &amp; sea</title>",
+				"&lt;img src=&#34;http://example.com/files/waves.jpg&#34;",
+			},
+		},
+		{
+			path:        "/jane/rss.xml",
+			contentType: "application/rss+xml",
+			contains: []string{
+				`<guid isPermaLink="true">http://example.com/jane/photos/sunset</guid>`,
+				"<pubDate>Sun, 20 Apr 2025 10:00:00 +0000</pubDate>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("got content type %q, want %q", got, tt.contentType)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestFeedCacheInvalidation(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test")
+	t.Setenv("UPLOADER_BASE_PATH", "")
+
+	e, repo := newFeedTestService()
+
+	get := func(host, path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s%s: got status %d, want %d", host, path, rec.Code, http.StatusOK)
+		}
+	}
+
+	send := func(method, path, body, userID, role string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+testToken(t, userID, role))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: got status %d, want %d: %s", method, path, rec.Code, http.StatusOK, rec.Body.String())
+		}
+	}
+
+	folderPatch := `{"folders":[{"id":"folder-owner","portfolio_id":"portfolio-owner","name":"Bali"}]}`
+
+	tests := []struct {
+		name    string
+		change  func()
+		lookups int
+	}{
+		{name: "cached", change: func() {}, lookups: 1},
+		{name: "username casing", change: func() { get("example.com", "/Jane/sitemap.xml") }, lookups: 1},
+		{name: "unknown host", change: func() {
+			get("evil.test", "/jane/sitemap.xml")
+			get("evil.test", "/jane/sitemap.xml")
+		}, lookups: 3},
+		{name: "folder patch", change: func() { send(http.MethodPatch, "/api/v1/portfolios", folderPatch, ownerID, model.RoleUser) }, lookups: 4},
+		{name: "admin folder patch", change: func() { send(http.MethodPatch, "/api/v1/portfolios", folderPatch, "admin", model.RoleAdmin) }, lookups: 5},
+		{name: "removed upload", change: func() {
+			send(http.MethodDelete, "/api/v1/uploads", `{"public_ids":["portfolios/owner/photo.jpg"]}`, ownerID, model.RoleUser)
+		}, lookups: 6},
+		{name: "another user's removed upload", change: func() {
+			send(http.MethodDelete, "/api/v1/uploads", `{"public_ids":["portfolios/intruder/photo.jpg"]}`, intruderID, model.RoleUser)
+		}, lookups: 6},
+		{name: "purge", change: func() {
+			send(http.MethodPost, "/api/v1/uploads/purge", `{"confirm":"PURGE portfolios/owner/"}`, ownerID, model.RoleUser)
+		}, lookups: 7},
+	}
+
+	get("example.com", "/jane/sitemap.xml")
+
+	for _, tt := range tests {
+		tt.change()
+		get("example.com", "/jane/sitemap.xml")
+
+		if repo.lookups != tt.lookups {
+			t.Fatalf("%s: got %d lookups, want %d", tt.name, repo.lookups, tt.lookups)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package router
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	zipMaxArchiveBytes     = 4 << 30
+	zipMaxEntries          = 5000
+	zipMaxEntryBytes       = 256 << 20
+	zipMaxTotalBytes       = 16 << 30
+	zipMaxCompressionRatio = 100
+	zipCaptionsFile        = "captions.csv"
+)
+
+var zipImageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+	".heic": true,
+	".heif": true,
+	".avif": true,
+}
+
+func (h *httpService) importZipHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	folder, status, err := h.findOwnedFolder(c.Request().Context(), session.ID, c.Param("id"))
+	if err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		logger.WithError(err).Error("failed to get file")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	if file.Size > zipMaxArchiveBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, response{Code: "file_too_large", Message: model.ErrFileTooLarge.Error()})
+	}
+
+	archivePath, err := saveTempFile(file, "ekspresi-import-*.zip")
+	if err != nil {
+		logger.WithError(err).Error("failed to store archive")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	job := model.ImportJob{
+		ID:        ulid.Make().String(),
+		UserID:    session.ID,
+		FolderID:  folder.ID,
+		Status:    model.ImportJobStatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := h.importJobRepo.Create(c.Request().Context(), job); err != nil {
+		os.Remove(archivePath)
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	go h.runZipImport(context.Background(), job, archivePath)
+
+	return c.JSON(http.StatusAccepted, response{Success: true, Data: job})
+}
+
+func (h *httpService) importJobHandler(c echo.Context) error {
+	session, err := authSession(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if status, err := h.authorize(c.Request().Context(), session, model.ResourceFolder, c.Param("id")); err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	job, err := h.importJobRepo.FindByID(c.Request().Context(), c.Param("jobId"))
+	if err != nil || job.FolderID != c.Param("id") {
+		return c.JSON(http.StatusNotFound, response{Message: "import job not found"})
+	}
+
+	if job.UserID != session.ID && session.Role != model.RoleAdmin {
+		return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: job})
+}
+
+// runZipImport unpacks archivePath into the job folder, appending images in
+// archive order after the folder's photos. Entries that look like zip bombs
+// are skipped.
+func (h *httpService) runZipImport(ctx context.Context, job model.ImportJob, archivePath string) {
+	logger := logrus.WithField("job_id", job.ID)
+
+	defer os.Remove(archivePath)
+
+	fail := func(err error) {
+		logger.WithError(err).Error("zip import failed")
+		job.Status = model.ImportJobStatusFailed
+		job.Error = err.Error()
+		h.importJobRepo.Update(ctx, job)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fail(fmt.Errorf("import panicked: %v", r))
+		}
+	}()
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer archive.Close()
+
+	if len(archive.File) > zipMaxEntries {
+		fail(errors.New("archive has too many entries"))
+		return
+	}
+
+	limits, err := h.uploadLimits(ctx, job.UserID)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	captions := readZipCaptions(archive.File)
+
+	var images []*zip.File
+
+	for _, f := range archive.File {
+		if isZipImage(f) {
+			images = append(images, f)
+		}
+	}
+
+	job.Status = model.ImportJobStatusRunning
+	job.Total = len(images)
+	h.importJobRepo.Update(ctx, job)
+
+	var totalBytes uint64
+
+	for _, f := range images {
+		totalBytes += f.UncompressedSize64
+
+		buf, err := readZipEntry(f, totalBytes)
+		if err != nil {
+			logger.WithField("entry", f.Name).WithError(err).Warn("skipped zip entry")
+			job.Skipped++
+		} else {
+			caption := captions[path.Base(f.Name)]
+
+			_, err = h.storePhoto(ctx, job.UserID, model.Photo{
+				FolderID: job.FolderID,
+				Caption:  caption.caption,
+				Alt:      caption.alt,
+			}, buf, limits, h.uploaderRepo.AppendPhoto)
+			if err != nil {
+				logger.WithField("entry", f.Name).WithError(err).Warn("failed to import zip entry")
+				job.Failed++
+			} else {
+				job.Imported++
+			}
+		}
+
+		job.Processed++
+		h.importJobRepo.Update(ctx, job)
+	}
+
+	job.Status = model.ImportJobStatusCompleted
+	h.importJobRepo.Update(ctx, job)
+}
+
+// findOwnedFolder returns the folder when it belongs to the user's portfolio,
+// otherwise the status to respond with.
+func (h *httpService) findOwnedFolder(ctx context.Context, userID, folderID string) (model.Folder, int, error) {
+	owners, err := h.ownershipRepo.FindOwners(ctx, model.ResourceFolder, []string{folderID})
+	if err != nil {
+		return model.Folder{}, http.StatusInternalServerError, err
+	}
+
+	owner, ok := owners[folderID]
+	if !ok {
+		return model.Folder{}, http.StatusNotFound, errors.New("folder not found")
+	}
+
+	if owner != userID {
+		return model.Folder{}, http.StatusForbidden, model.ErrForbidden
+	}
+
+	folder, err := h.portfolioRepo.FindFolderByID(ctx, folderID)
+	if err != nil {
+		return model.Folder{}, http.StatusInternalServerError, err
+	}
+
+	return folder, 0, nil
+}
+
+func isZipImage(f *zip.File) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(path.Base(f.Name), ".") {
+		return false
+	}
+
+	return zipImageExtensions[strings.ToLower(path.Ext(f.Name))]
+}
+
+// readZipEntry reads an entry, refusing sizes and compression ratios typical
+// of zip bombs. The declared size is not trusted while decompressing.
+func readZipEntry(f *zip.File, totalBytes uint64) ([]byte, error) {
+	if f.UncompressedSize64 > zipMaxEntryBytes || totalBytes > zipMaxTotalBytes {
+		return nil, errors.New("entry too large")
+	}
+
+	if f.CompressedSize64 > 0 && f.UncompressedSize64/f.CompressedSize64 > zipMaxCompressionRatio {
+		return nil, errors.New("suspicious compression ratio")
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(rc, int64(f.UncompressedSize64)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(buf)) > f.UncompressedSize64 {
+		return nil, errors.New("entry larger than declared")
+	}
+
+	if imaging.Sniff(buf) == "" {
+		return nil, model.ErrUnsupportedMediaType
+	}
+
+	return buf, nil
+}
+
+type zipCaption struct {
+	caption string
+	alt     string
+}
+
+// readZipCaptions parses an optional captions.csv with filename, caption and
+// alt columns.
+func readZipCaptions(files []*zip.File) map[string]zipCaption {
+	captions := map[string]zipCaption{}
+
+	for _, f := range files {
+		if !strings.EqualFold(path.Base(f.Name), zipCaptionsFile) || f.UncompressedSize64 > 1<<20 {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return captions
+		}
+		defer rc.Close()
+
+		reader := csv.NewReader(io.LimitReader(rc, 1<<20))
+		reader.FieldsPerRecord = -1
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			logrus.WithError(err).Warn("failed to parse captions csv")
+			return captions
+		}
+
+		for _, record := range records {
+			if len(record) < 2 || strings.EqualFold(record[0], "filename") {
+				continue
+			}
+
+			caption := zipCaption{caption: record[1]}
+			if len(record) > 2 {
+				caption.alt = record[2]
+			}
+
+			captions[path.Base(record[0])] = caption
+		}
+
+		return captions
+	}
+
+	return captions
+}
+
+// saveTempFile copies an uploaded file to disk so it can be read after the
+// request ends.
+func saveTempFile(file *multipart.FileHeader, pattern string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+
+	return dst.Name(), nil
+}
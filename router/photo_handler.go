@@ -0,0 +1,76 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// replacePhotoFileHandler swaps a photo's file for a re-edited one. The
+// photo keeps its ID, folder, position, caption and alt text, so anything
+// keyed on the ID survives; the old asset goes to the deletion queue.
+func (h *httpService) replacePhotoFileHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := logrus.WithContext(ctx)
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if status, err := h.authorize(ctx, session, model.ResourcePhoto, c.Param("id")); err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		logger.WithError(err).Error("failed to get file")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	photo, err := h.uploaderRepo.FindByID(ctx, c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, response{Message: "photo not found"})
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("failed to find photo")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	// Limits and usage belong to the owner, also when an admin replaces.
+	userID := photo.UserID
+	if userID == "" {
+		userID = session.ID
+	}
+
+	kept := model.Photo{
+		ID:        photo.ID,
+		FolderID:  photo.FolderID,
+		Alt:       photo.Alt,
+		Caption:   photo.Caption,
+		SortIndex: photo.SortIndex,
+		CreatedAt: photo.CreatedAt,
+	}
+
+	if isVideoFile(file) {
+		return h.uploadVideo(c, userID, kept, file, h.uploaderRepo.ReplacePhoto)
+	}
+
+	return h.uploadImage(c, userID, kept, file, h.uploaderRepo.ReplacePhoto)
+}
+
+// createdAt keeps the creation time of a photo whose file is replaced.
+func createdAt(photo model.Photo) time.Time {
+	if photo.CreatedAt.IsZero() {
+		return time.Now()
+	}
+
+	return photo.CreatedAt
+}
@@ -1,6 +1,9 @@
 package router
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/labstack/echo/v4"
 	"github.com/notblessy/ekspresi-core/model"
 	"github.com/sirupsen/logrus"
@@ -27,11 +30,110 @@ func (h *httpService) patchPortfolioHandler(c echo.Context) error {
 		return c.JSON(401, response{Message: "unauthorized"})
 	}
 
+	owners, status, err := h.authorizePortfolioPatch(c.Request().Context(), session, input)
+	if err != nil {
+		logger.WithError(err).Error("failed to authorize portfolio patch")
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	if ws := input.WatermarkSettings; ws != nil {
+		if err := c.Validate(ws); err != nil {
+			logger.WithError(err).Error("invalid watermark settings")
+			return c.JSON(400, response{Message: err.Error()})
+		}
+
+		if ws.LogoPublicID != "" && !ownsPublicID(session.ID, ws.LogoPublicID) {
+			return c.JSON(400, response{Message: "invalid watermark logo"})
+		}
+	}
+
+	if input.Theme != "" || input.ThemeSettings != nil {
+		if status, err := h.validatePortfolioTheme(c.Request().Context(), input.Portfolio); err != nil {
+			return c.JSON(status, response{Message: err.Error()})
+		}
+	}
+
 	err = h.portfolioRepo.Patch(c.Request().Context(), input)
 	if err != nil {
 		logger.WithError(err).Error("failed to patch portfolio")
 		return c.JSON(500, response{Message: err.Error()})
 	}
 
+	for _, owner := range owners {
+		h.feedCache.invalidateUser(owner)
+	}
+
 	return c.JSON(200, response{Success: true})
 }
+
+// authorizePortfolioPatch checks that every portfolio, profile, folder and
+// photo the patch touches belongs to the session user. It returns the users
+// owning them, which differ from the caller when an admin patches.
+func (h *httpService) authorizePortfolioPatch(ctx context.Context, session jwtClaims, input model.PortfolioType) ([]string, int, error) {
+	// Deleted photos also drop their stored assets, which may have no row.
+	for _, publicID := range input.DeletedPhotos {
+		if session.Role != model.RoleAdmin && !ownsPublicID(session.ID, publicID) {
+			return nil, http.StatusForbidden, model.ErrForbidden
+		}
+	}
+
+	portfolioIDs := []string{input.ID}
+	folderIDs := append([]string{}, input.DeletedFolders...)
+	publicIDs := append([]string{}, input.DeletedPhotos...)
+
+	for _, folder := range input.Folders {
+		portfolioIDs = append(portfolioIDs, folder.PortfolioID)
+		folderIDs = append(folderIDs, folder.ID)
+		publicIDs = append(publicIDs, folder.GetPhotoPublicIDs()...)
+	}
+
+	checks := []struct {
+		kind string
+		ids  []string
+	}{
+		{model.ResourcePortfolio, portfolioIDs},
+		{model.ResourceProfile, []string{input.Profiles.ID}},
+		{model.ResourceFolder, folderIDs},
+		{model.ResourceAsset, publicIDs},
+	}
+
+	for _, check := range checks {
+		if status, err := h.authorize(ctx, session, check.kind, check.ids...); err != nil {
+			return nil, status, err
+		}
+	}
+
+	if session.Role != model.RoleAdmin {
+		return []string{session.ID}, 0, nil
+	}
+
+	seen := map[string]bool{}
+	var owners []string
+
+	for _, check := range checks {
+		var ids []string
+		for _, id := range check.ids {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		found, err := h.ownershipRepo.FindOwners(ctx, check.kind, ids)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+
+		for _, owner := range found {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	return owners, 0, nil
+}
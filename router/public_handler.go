@@ -0,0 +1,66 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// publicPortfolioHandler serves a published portfolio to visitors. Unknown
+// and unpublished portfolios are both reported as not found, unless the
+// username was recently changed.
+func (h *httpService) publicPortfolioHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	username := c.Param("username")
+
+	portfolio, err := h.portfolioRepo.FindPublishedByUsername(c.Request().Context(), username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		redirect, err := h.findUsernameRedirect(c, username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response{Message: "portfolio not found"})
+		}
+
+		if err != nil {
+			logger.WithError(err).Error("failed to find username redirect")
+			return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+		}
+
+		return c.Redirect(http.StatusFound, redirect.apiPath())
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("failed to find published portfolio")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=60")
+
+	return c.JSON(http.StatusOK, response{
+		Success: true,
+		Data:    model.NewPublicPortfolio(username, portfolio),
+	})
+}
+
+// findUsernameRedirect returns the site of whoever released username, under
+// their current username. A username that was never released, or is current
+// again, has no redirect and gorm.ErrRecordNotFound is returned.
+//
+// Redirects only last for the grace period before the name can be claimed
+// again, so they are sent as 302s that browsers do not cache.
+func (h *httpService) findUsernameRedirect(c echo.Context, username string) (site, error) {
+	current, err := h.usernameRepo.FindRedirect(c.Request().Context(), username)
+	if err != nil {
+		return site{}, err
+	}
+
+	if current == model.NormalizeUsername(username) {
+		return site{}, gorm.ErrRecordNotFound
+	}
+
+	return newSite(c, current), nil
+}
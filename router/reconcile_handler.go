@@ -0,0 +1,37 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// reconcileHandler reports orphaned assets and rows. Pass dry_run=false to
+// delete them.
+func (h *httpService) reconcileHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get auth session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if session.Role != model.RoleAdmin {
+		return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+	}
+
+	report, err := h.assetReconciler.Reconcile(c.Request().Context(), c.QueryParam("dry_run") != "false")
+	if err != nil {
+		logger.WithError(err).Error("failed to reconcile assets")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error(), Data: report})
+	}
+
+	for _, userID := range report.UserIDs {
+		h.feedCache.invalidateUser(userID)
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: report})
+}
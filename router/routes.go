@@ -13,10 +13,26 @@ type httpService struct {
 	membershipRepo     model.MembershipRepository
 	portfolioRepo      model.PortfolioRepository
 	uploaderRepo       model.UploaderRepository
+	uploadSessionRepo  model.UploadSessionRepository
+	importJobRepo      model.ImportJobRepository
+	assetReconciler    model.AssetReconciler
+	uploadPurger       model.UploadPurger
+	usageRepo          model.UsageRepository
+	assetDeletionRepo  model.AssetDeletionRepository
+	variantRenderer    model.VariantRenderer
+	ownershipRepo      model.OwnershipRepository
+	usernameRepo       model.UsernameRepository
+	customDomainRepo   model.CustomDomainRepository
+	domainVerifier     model.DomainVerifier
+	feedCache          *feedCache
+	duplicateThreshold int
+	duplicatePolicy    string
 }
 
 func NewHTTPService() *httpService {
-	return &httpService{}
+	return &httpService{
+		feedCache: newFeedCache(feedCacheTTL),
+	}
 }
 
 func (h *httpService) RegisterPostgres(db *gorm.DB) {
@@ -43,6 +59,57 @@ func (h *httpService) RegisterUploaderRepository(repo model.UploaderRepository)
 	h.uploaderRepo = repo
 }
 
+func (h *httpService) RegisterUploadSessionRepository(repo model.UploadSessionRepository) {
+	h.uploadSessionRepo = repo
+}
+
+func (h *httpService) RegisterImportJobRepository(repo model.ImportJobRepository) {
+	h.importJobRepo = repo
+}
+
+func (h *httpService) RegisterAssetReconciler(reconciler model.AssetReconciler) {
+	h.assetReconciler = reconciler
+}
+
+func (h *httpService) RegisterUploadPurger(purger model.UploadPurger) {
+	h.uploadPurger = purger
+}
+
+func (h *httpService) RegisterUsageRepository(repo model.UsageRepository) {
+	h.usageRepo = repo
+}
+
+func (h *httpService) RegisterAssetDeletionRepository(repo model.AssetDeletionRepository) {
+	h.assetDeletionRepo = repo
+}
+
+func (h *httpService) RegisterVariantRenderer(renderer model.VariantRenderer) {
+	h.variantRenderer = renderer
+}
+
+func (h *httpService) RegisterOwnershipRepository(repo model.OwnershipRepository) {
+	h.ownershipRepo = repo
+}
+
+func (h *httpService) RegisterUsernameRepository(repo model.UsernameRepository) {
+	h.usernameRepo = repo
+}
+
+func (h *httpService) RegisterCustomDomainRepository(repo model.CustomDomainRepository) {
+	h.customDomainRepo = repo
+}
+
+func (h *httpService) RegisterDomainVerifier(verifier model.DomainVerifier) {
+	h.domainVerifier = verifier
+}
+
+// RegisterDuplicateDetection sets the maximum hash distance treated as a
+// duplicate and whether duplicates are only reported or rejected.
+func (h *httpService) RegisterDuplicateDetection(threshold int, policy string) {
+	h.duplicateThreshold = threshold
+	h.duplicatePolicy = policy
+}
+
 func (h *httpService) Router(e *echo.Echo) {
 	e.GET("/ping", h.ping)
 	e.GET("/health", h.health)
@@ -52,9 +119,24 @@ func (h *httpService) Router(e *echo.Echo) {
 	auth := v1.Group("/auth")
 	auth.POST("/login/google", h.loginWithGoogleHandler)
 
+	v1.GET("/assets/*", h.assetHandler, NewJWTMiddleware().OptionalJWT)
+	v1.GET("/folders/:id/download", h.downloadFolderHandler, NewJWTMiddleware().OptionalJWT)
+
+	public := v1.Group("/public")
+	public.GET("/portfolios/:username", h.publicPortfolioHandler)
+
+	e.GET("/:username", h.sitePortfolioHandler)
+	e.GET("/:username/folders/:id", h.siteFolderHandler)
+	e.GET("/:username/photos/:id", h.sitePhotoHandler)
+	e.GET("/:username/sitemap.xml", h.sitemapHandler)
+	e.GET("/:username/feed.xml", h.atomFeedHandler)
+	e.GET("/:username/rss.xml", h.rssFeedHandler)
+
 	v1.Use(NewJWTMiddleware().ValidateJWT)
 	users := v1.Group("/users")
 	users.GET("/me", h.profileHandler)
+	users.GET("/me/usage", h.usageHandler)
+	users.PUT("/me/username", h.changeUsernameHandler)
 
 	membershipPlans := v1.Group("/membership-plans")
 	membershipPlans.POST("", h.createMembershipPlan)
@@ -65,11 +147,41 @@ func (h *httpService) Router(e *echo.Echo) {
 
 	portfolios := v1.Group("/portfolios")
 	portfolios.PATCH("", h.patchPortfolioHandler)
+	portfolios.GET("/duplicates", h.duplicatesHandler)
+	portfolios.POST("/watermark/logo", h.uploadWatermarkLogoHandler)
+
+	v1.GET("/themes", h.findThemesHandler)
+
+	folders := v1.Group("/folders")
+	folders.POST("/:id/import-zip", h.importZipHandler)
+	folders.GET("/:id/import-zip/:jobId", h.importJobHandler)
+
+	photos := v1.Group("/photos")
+	photos.PUT("/:id/file", h.replacePhotoFileHandler)
+
+	domains := v1.Group("/domains")
+	domains.GET("", h.findDomainsHandler)
+	domains.POST("", h.createDomainHandler)
+	domains.POST("/:id/verify", h.verifyDomainHandler)
+	domains.DELETE("/:id", h.deleteDomainHandler)
+
+	admin := v1.Group("/admin")
+	admin.POST("/reconcile", h.reconcileHandler)
+	admin.GET("/asset-deletions", h.assetDeletionStatsHandler)
+	admin.POST("/asset-deletions/retry", h.retryAssetDeletionsHandler)
+	admin.GET("/metrics", h.metricsHandler)
 
 	upload := v1.Group("/uploads")
 	upload.POST("", h.uploadPhotoHandler)
 	upload.DELETE("", h.bulkRemovePhotosHandler)
-	upload.POST("/flush", h.flushHandler)
+	upload.POST("/purge", h.purgeHandler)
+	upload.POST("/sign", h.signUploadHandler)
+	upload.POST("/confirm", h.confirmUploadHandler)
+	upload.OPTIONS("/tus", h.tusOptionsHandler)
+	upload.POST("/tus", h.tusCreateHandler)
+	upload.HEAD("/tus/:id", h.tusHeadHandler)
+	upload.PATCH("/tus/:id", h.tusPatchHandler)
+	upload.DELETE("/tus/:id", h.tusDeleteHandler)
 }
 
 func (h *httpService) ping(c echo.Context) error {
@@ -0,0 +1,681 @@
+package router
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	siteName              = "Ekspresi"
+	siteDescriptionLength = 160
+	sitePhotoMaxWidth     = 1600
+)
+
+//go:embed templates/*.html
+var siteTemplateFiles embed.FS
+
+// siteTemplates holds one template set per page, each combined with the
+// shared layout.
+var siteTemplates = func() map[string]*template.Template {
+	pages := map[string]*template.Template{}
+
+	for _, page := range []string{"portfolio", "folder", "photo", "error"} {
+		pages[page] = template.Must(template.ParseFS(siteTemplateFiles, "templates/layout.html", "templates/"+page+".html"))
+	}
+
+	return pages
+}()
+
+var siteColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// siteFormats orders picture sources so browsers pick the smallest format
+// they support.
+var siteFormats = []string{"avif", "webp", "jpeg", "png"}
+
+// sitePage is what the layout and page templates render.
+type sitePage struct {
+	Title       string
+	Description string
+	Canonical   string
+	Feed        string
+	Type        string
+	SiteName    string
+	Home        string
+	Layout      string
+	Image       *siteImage
+	JSONLD      template.JS
+	ThemeCSS    template.CSS
+	Portfolio   model.PublicPortfolio
+	Folders     []siteFolder
+	Folder      siteFolder
+	Photo       sitePhoto
+	Details     []string
+	Previous    *sitePhoto
+	Next        *sitePhoto
+}
+
+// siteImage is the picture shared on social cards.
+type siteImage struct {
+	Src    string
+	Alt    string
+	Width  int
+	Height int
+}
+
+type siteFolder struct {
+	model.PublicFolder
+	URL         string
+	DownloadURL string
+	GalleryCSS  template.CSS
+	Cover       *sitePhoto
+	Photos      []sitePhoto
+}
+
+// sitePhoto is a photo with what responsive markup needs: one srcset per
+// variant format, the intrinsic size of the largest rendition to reserve
+// space and the sizes hint for the column it is shown in.
+type sitePhoto struct {
+	model.PublicPhoto
+	URL        string
+	Width      int
+	Height     int
+	Sizes      string
+	Loading    string
+	Background template.CSS
+	Sources    []siteSource
+}
+
+type siteSource struct {
+	Type   string
+	Srcset string
+}
+
+// site resolves the URLs of a portfolio's pages. On a custom domain the
+// portfolio is served from the root, otherwise under its username.
+type site struct {
+	origin   string
+	basePath string
+}
+
+func newSite(c echo.Context, username string) site {
+	s := site{
+		origin:   c.Scheme() + "://" + c.Request().Host,
+		basePath: "/" + url.PathEscape(username),
+	}
+
+	if _, ok := c.Get("custom_domain").(string); ok {
+		s.basePath = ""
+	}
+
+	return s
+}
+
+func (s site) path(p string) string {
+	if s.basePath+p == "" {
+		return "/"
+	}
+
+	return s.basePath + p
+}
+
+// apiPath is the path of the portfolio's JSON.
+func (s site) apiPath() string {
+	if s.basePath == "" {
+		return "/api/v1/public/portfolio"
+	}
+
+	return "/api/v1/public/portfolios" + s.basePath
+}
+
+func (s site) url(p string) string {
+	return s.origin + s.path(p)
+}
+
+// absolute makes storage URLs served by this host, such as local files,
+// usable outside the page.
+func (s site) absolute(src string) string {
+	if strings.HasPrefix(src, "/") && !strings.HasPrefix(src, "//") {
+		return s.origin + src
+	}
+
+	return src
+}
+
+// sitePortfolioHandler renders the home page of a published portfolio.
+func (h *httpService) sitePortfolioHandler(c echo.Context) error {
+	pt, ok, err := h.findSitePortfolio(c, "")
+	if !ok {
+		return err
+	}
+
+	portfolio := model.NewPublicPortfolio(model.NormalizeUsername(c.Param("username")), pt)
+	s := newSite(c, portfolio.Username)
+	page := newSitePage(s, portfolio)
+	page.Type = "profile"
+	page.Canonical = s.url("")
+	page.Title = page.SiteName
+	if portfolio.Profiles.Title != "" {
+		page.Title += " · " + portfolio.Profiles.Title
+	}
+
+	page.Description = summary(firstNonEmpty(portfolio.Profiles.Bio, portfolio.Description, "Photography portfolio of "+page.SiteName), siteDescriptionLength)
+
+	for _, f := range portfolio.Folders {
+		folder := newSiteFolder(s, portfolio, f)
+		page.Folders = append(page.Folders, folder)
+
+		if page.Image == nil && folder.Cover != nil {
+			page.Image = newSiteImage(s, *folder.Cover)
+		}
+	}
+
+	person := sitePerson(s, portfolio)
+	person["@context"] = "https://schema.org"
+	page.JSONLD = siteJSONLD(person)
+
+	return renderSite(c, http.StatusOK, "portfolio", page)
+}
+
+// siteFolderHandler renders a folder as a gallery.
+func (h *httpService) siteFolderHandler(c echo.Context) error {
+	pt, ok, err := h.findSitePortfolio(c, "/folders/"+c.Param("id"))
+	if !ok {
+		return err
+	}
+
+	portfolio := model.NewPublicPortfolio(model.NormalizeUsername(c.Param("username")), pt)
+	s := newSite(c, portfolio.Username)
+	page := newSitePage(s, portfolio)
+
+	i := slices.IndexFunc(portfolio.Folders, func(f model.PublicFolder) bool { return f.ID == c.Param("id") })
+	if i < 0 {
+		return renderSiteError(c, http.StatusNotFound, "Page not found", "This gallery does not exist or is no longer published.")
+	}
+
+	page.Folder = newSiteFolder(s, portfolio, portfolio.Folders[i])
+	page.Canonical = page.Folder.URL
+	page.Title = page.Folder.Name + " | " + page.SiteName
+	page.Description = summary(firstNonEmpty(page.Folder.Description, fmt.Sprintf("%d photos by %s", len(page.Folder.Photos), page.SiteName)), siteDescriptionLength)
+
+	if page.Folder.Cover != nil {
+		page.Image = newSiteImage(s, *page.Folder.Cover)
+	}
+
+	media := []map[string]interface{}{}
+	for _, photo := range page.Folder.Photos {
+		media = append(media, siteMediaObject(s, photo))
+	}
+
+	page.JSONLD = siteJSONLD(map[string]interface{}{
+		"@context":        "https://schema.org",
+		"@type":           "ImageGallery",
+		"name":            page.Folder.Name,
+		"description":     page.Description,
+		"url":             page.Canonical,
+		"author":          sitePerson(s, portfolio),
+		"associatedMedia": media,
+	})
+
+	return renderSite(c, http.StatusOK, "folder", page)
+}
+
+// sitePhotoHandler renders a single photo with its visible metadata and
+// links to its neighbours in the folder.
+func (h *httpService) sitePhotoHandler(c echo.Context) error {
+	pt, ok, err := h.findSitePortfolio(c, "/photos/"+c.Param("id"))
+	if !ok {
+		return err
+	}
+
+	portfolio := model.NewPublicPortfolio(model.NormalizeUsername(c.Param("username")), pt)
+	s := newSite(c, portfolio.Username)
+	page := newSitePage(s, portfolio)
+
+	found := false
+
+	for _, f := range portfolio.Folders {
+		folder := newSiteFolder(s, portfolio, f)
+
+		i := slices.IndexFunc(folder.Photos, func(p sitePhoto) bool { return p.ID == c.Param("id") })
+		if i < 0 {
+			continue
+		}
+
+		page.Folder = folder
+		page.Photo = folder.Photos[i]
+		page.Photo.Sizes = fmt.Sprintf("(max-width: %dpx) 100vw, %dpx", sitePhotoMaxWidth, sitePhotoMaxWidth)
+		page.Photo.Loading = "eager"
+
+		if i > 0 {
+			page.Previous = &folder.Photos[i-1]
+		}
+
+		if i < len(folder.Photos)-1 {
+			page.Next = &folder.Photos[i+1]
+		}
+
+		found = true
+
+		break
+	}
+
+	if !found {
+		return renderSiteError(c, http.StatusNotFound, "Page not found", "This photo does not exist or is no longer published.")
+	}
+
+	page.Canonical = page.Photo.URL
+	page.Title = summary(firstNonEmpty(page.Photo.Caption, page.Photo.Alt, page.Folder.Name), 60) + " | " + page.SiteName
+	page.Description = summary(firstNonEmpty(page.Photo.Caption, page.Photo.Alt, "From "+page.Folder.Name+" by "+page.SiteName), siteDescriptionLength)
+	page.Image = newSiteImage(s, page.Photo)
+	page.Details = photoDetails(page.Photo.Metadata)
+
+	object := siteMediaObject(s, page.Photo)
+	object["@context"] = "https://schema.org"
+	object["url"] = page.Canonical
+	object["author"] = sitePerson(s, portfolio)
+	object["isPartOf"] = map[string]interface{}{
+		"@type": "ImageGallery",
+		"name":  page.Folder.Name,
+		"url":   page.Folder.URL,
+	}
+	page.JSONLD = siteJSONLD(object)
+
+	return renderSite(c, http.StatusOK, "photo", page)
+}
+
+// findSitePortfolio loads the published portfolio of the username in the
+// path. When there is none, the response is already written and ok is
+// false: a redirect to the owner's current username keeping suffix, or a
+// not found page.
+func (h *httpService) findSitePortfolio(c echo.Context, suffix string) (model.PortfolioType, bool, error) {
+	ctx := c.Request().Context()
+	logger := logrus.WithContext(ctx)
+
+	username := c.Param("username")
+
+	portfolio, err := h.portfolioRepo.FindPublishedByUsername(ctx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		redirect, err := h.findUsernameRedirect(c, username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.PortfolioType{}, false, renderSiteError(c, http.StatusNotFound, "Page not found", "This portfolio does not exist or is no longer published.")
+		}
+
+		if err != nil {
+			logger.WithError(err).Error("failed to find username redirect")
+			return model.PortfolioType{}, false, renderSiteError(c, http.StatusInternalServerError, "Something went wrong", "Please try again in a moment.")
+		}
+
+		return model.PortfolioType{}, false, c.Redirect(http.StatusFound, redirect.path(suffix))
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("failed to find published portfolio")
+		return model.PortfolioType{}, false, renderSiteError(c, http.StatusInternalServerError, "Something went wrong", "Please try again in a moment.")
+	}
+
+	return portfolio, true, nil
+}
+
+func newSitePage(s site, portfolio model.PublicPortfolio) sitePage {
+	page := sitePage{
+		Type:      "website",
+		SiteName:  firstNonEmpty(portfolio.Profiles.Name, portfolio.Username),
+		Home:      s.path(""),
+		Feed:      s.url("/feed.xml"),
+		Portfolio: portfolio,
+		ThemeCSS:  themeCSS(portfolio),
+		Layout:    "grid",
+	}
+
+	if layout, ok := portfolio.ThemeTokens["layout"].(string); ok {
+		page.Layout = layout
+	}
+
+	return page
+}
+
+func newSiteFolder(s site, portfolio model.PublicPortfolio, f model.PublicFolder) siteFolder {
+	columns := max(firstPositive(f.Columns, portfolio.Columns, 3), 1)
+
+	folder := siteFolder{
+		PublicFolder: f,
+		URL:          s.url("/folders/" + url.PathEscape(f.ID)),
+		DownloadURL:  "/api/v1/folders/" + url.PathEscape(f.ID) + "/download",
+		GalleryCSS:   template.CSS(fmt.Sprintf("--columns: %d;", columns)),
+	}
+
+	if gap := firstPositive(f.Gap, portfolio.Gap); gap > 0 {
+		folder.GalleryCSS += template.CSS(fmt.Sprintf(" --gallery-gap: %dpx;", gap))
+	}
+
+	sizes := fmt.Sprintf("(max-width: 640px) 100vw, %dvw", max(100/columns, 1))
+
+	for i, p := range f.Photos {
+		photo := newSitePhoto(s, p, sizes)
+		if i < columns {
+			photo.Loading = "eager"
+		}
+
+		folder.Photos = append(folder.Photos, photo)
+	}
+
+	if len(folder.Photos) > 0 {
+		cover := folder.Photos[0]
+		if f.CoverID >= 0 && f.CoverID < len(folder.Photos) {
+			cover = folder.Photos[f.CoverID]
+		}
+
+		cover.Sizes = "(max-width: 640px) 100vw, 33vw"
+		folder.Cover = &cover
+	}
+
+	return folder
+}
+
+func newSitePhoto(s site, p model.PublicPhoto, sizes string) sitePhoto {
+	photo := sitePhoto{
+		PublicPhoto: p,
+		URL:         s.url("/photos/" + url.PathEscape(p.ID)),
+		Width:       p.Width,
+		Height:      p.Height,
+		Sizes:       sizes,
+		Loading:     "lazy",
+	}
+
+	photo.Alt = firstNonEmpty(p.Alt, p.Caption)
+
+	if siteColorPattern.MatchString(p.DominantColor) {
+		photo.Background = template.CSS("background-color: " + p.DominantColor)
+	}
+
+	formats := map[string][]string{}
+	var order []string
+
+	for _, v := range p.Variants {
+		if v.Width == 0 || v.Src == "" {
+			continue
+		}
+
+		if v.Width > photo.Width || photo.Width == 0 {
+			photo.Width, photo.Height = v.Width, v.Height
+		}
+
+		if _, ok := formats[v.Format]; !ok {
+			order = append(order, v.Format)
+		}
+
+		formats[v.Format] = append(formats[v.Format], fmt.Sprintf("%s %dw", v.Src, v.Width))
+	}
+
+	slices.SortStableFunc(order, func(a, b string) int {
+		return siteFormatRank(a) - siteFormatRank(b)
+	})
+
+	for _, format := range order {
+		photo.Sources = append(photo.Sources, siteSource{
+			Type:   "image/" + format,
+			Srcset: strings.Join(formats[format], ", "),
+		})
+	}
+
+	return photo
+}
+
+func siteFormatRank(format string) int {
+	if i := slices.Index(siteFormats, format); i >= 0 {
+		return i
+	}
+
+	return len(siteFormats)
+}
+
+// photoDetails lists the visible metadata of a photo for its caption line.
+func photoDetails(m model.PhotoMetadata) []string {
+	var details []string
+
+	if camera := strings.TrimSpace(m.CameraMake + " " + m.CameraModel); camera != "" {
+		details = append(details, camera)
+	}
+
+	if m.Lens != "" {
+		details = append(details, m.Lens)
+	}
+
+	if m.FocalLength > 0 {
+		details = append(details, fmt.Sprintf("%gmm", m.FocalLength))
+	}
+
+	if m.Aperture > 0 {
+		details = append(details, fmt.Sprintf("ƒ/%g", m.Aperture))
+	}
+
+	if m.ShutterSpeed != "" {
+		details = append(details, m.ShutterSpeed+"s")
+	}
+
+	if m.ISO > 0 {
+		details = append(details, fmt.Sprintf("ISO %d", m.ISO))
+	}
+
+	if m.CapturedAt != nil {
+		details = append(details, m.CapturedAt.Format("2 January 2006"))
+	}
+
+	if m.Copyright != "" {
+		details = append(details, m.Copyright)
+	}
+
+	return details
+}
+
+// newSiteImage picks the picture of a social card; videos use their poster.
+func newSiteImage(s site, photo sitePhoto) *siteImage {
+	src := photo.Src
+	if photo.MediaType == "video" {
+		src = photo.PosterSrc
+	}
+
+	if src == "" {
+		return nil
+	}
+
+	return &siteImage{
+		Src:    s.absolute(src),
+		Alt:    photo.Alt,
+		Width:  photo.Width,
+		Height: photo.Height,
+	}
+}
+
+// themeCSS turns the resolved theme tokens into custom properties for the
+// layout's stylesheet. Tokens are validated when saved, so their values are
+// safe to emit.
+func themeCSS(portfolio model.PublicPortfolio) template.CSS {
+	theme, ok := model.FindTheme(portfolio.Theme)
+	if !ok {
+		theme, _ = model.FindTheme(model.DefaultTheme)
+	}
+
+	var css strings.Builder
+
+	for _, token := range theme.Tokens {
+		value, ok := portfolio.ThemeTokens[token.Name]
+		if !ok {
+			continue
+		}
+
+		name := "--" + strings.ReplaceAll(token.Name, "_", "-")
+
+		switch token.Type {
+		case model.ThemeTokenColor:
+			fmt.Fprintf(&css, "%s: %v; ", name, value)
+		case model.ThemeTokenFont:
+			fmt.Fprintf(&css, "%s: %q; ", name, value)
+		case model.ThemeTokenSpacing:
+			fmt.Fprintf(&css, "%s: %vpx; ", name, value)
+		}
+	}
+
+	return template.CSS(strings.TrimSpace(css.String()))
+}
+
+func sitePerson(s site, portfolio model.PublicPortfolio) map[string]interface{} {
+	person := map[string]interface{}{
+		"@type": "Person",
+		"name":  firstNonEmpty(portfolio.Profiles.Name, portfolio.Username),
+		"url":   s.url(""),
+	}
+
+	if portfolio.Profiles.Title != "" {
+		person["jobTitle"] = portfolio.Profiles.Title
+	}
+
+	if portfolio.Profiles.Bio != "" {
+		person["description"] = portfolio.Profiles.Bio
+	}
+
+	if portfolio.Profiles.Email != "" {
+		person["email"] = portfolio.Profiles.Email
+	}
+
+	var sameAs []string
+	if portfolio.Profiles.Instagram != "" {
+		sameAs = append(sameAs, "https://instagram.com/"+portfolio.Profiles.Instagram)
+	}
+
+	if portfolio.Profiles.Website != "" {
+		sameAs = append(sameAs, portfolio.Profiles.Website)
+	}
+
+	if len(sameAs) > 0 {
+		person["sameAs"] = sameAs
+	}
+
+	return person
+}
+
+// siteMediaObject describes a photo as an ImageObject, or a video as a
+// VideoObject.
+func siteMediaObject(s site, photo sitePhoto) map[string]interface{} {
+	object := map[string]interface{}{
+		"@type":           "ImageObject",
+		"contentUrl":      s.absolute(photo.Src),
+		"url":             photo.URL,
+		"uploadDate":      photo.CreatedAt.Format("2006-01-02"),
+		"width":           photo.Width,
+		"height":          photo.Height,
+		"caption":         photo.Caption,
+		"description":     photo.Alt,
+		"creditText":      photo.Metadata.Creator,
+		"copyrightNotice": photo.Metadata.Copyright,
+	}
+
+	if photo.MediaType == "video" {
+		object["@type"] = "VideoObject"
+		object["name"] = firstNonEmpty(photo.Caption, photo.Alt, photo.ID)
+		object["thumbnailUrl"] = s.absolute(photo.PosterSrc)
+		object["duration"] = fmt.Sprintf("PT%dS", photo.DurationMS/1000)
+	}
+
+	for key, value := range object {
+		if value == "" || value == 0 {
+			delete(object, key)
+		}
+	}
+
+	return object
+}
+
+// siteJSONLD encodes structured data for a script tag. json.Marshal escapes
+// <, > and &, so the result cannot close the tag.
+func siteJSONLD(data interface{}) template.JS {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+
+	return template.JS(b)
+}
+
+func renderSite(c echo.Context, status int, name string, page sitePage) error {
+	var buf bytes.Buffer
+
+	if err := siteTemplates[name].ExecuteTemplate(&buf, "layout", page); err != nil {
+		logrus.WithContext(c.Request().Context()).WithError(err).WithField("page", name).Error("failed to render page")
+		return c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	if status == http.StatusOK {
+		c.Response().Header().Set("Cache-Control", "public, max-age=60")
+	}
+
+	return c.HTMLBlob(status, buf.Bytes())
+}
+
+func renderSiteError(c echo.Context, status int, title, description string) error {
+	return renderSite(c, status, "error", sitePage{
+		Title:       title,
+		Description: description,
+		Type:        "website",
+		SiteName:    siteName,
+		Home:        "/",
+		Layout:      "grid",
+		ThemeCSS:    themeCSS(model.PublicPortfolio{ThemeTokens: defaultThemeTokens()}),
+	})
+}
+
+func defaultThemeTokens() model.ThemeSettings {
+	theme, _ := model.FindTheme(model.DefaultTheme)
+	return theme.Resolve(nil)
+}
+
+// summary collapses whitespace and shortens s to at most n runes, cutting at
+// a word boundary when there is one.
+func summary(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	cut := string([]rune(s)[:n-1])
+	if i := strings.LastIndex(cut, " "); i > n/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+
+	return 0
+}
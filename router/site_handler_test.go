@@ -0,0 +1,52 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestUsernameRedirect(t *testing.T) {
+	h := NewHTTPService()
+	h.RegisterPortfolioRepository(&fakePortfolioRepository{})
+	h.RegisterUsernameRepository(fakeUsernameRepository{redirects: map[string]string{"old": "new"}})
+
+	e := echo.New()
+	e.Pre(NewCustomDomainMiddleware(&fakeCustomDomainRepository{usernames: map[string]string{"photos.example.com": "old"}}, []string{"example.com"}, time.Minute).Resolve)
+	h.Router(e)
+
+	tests := []struct {
+		name     string
+		host     string
+		path     string
+		location string
+	}{
+		{name: "site", host: "example.com", path: "/old/photos/p1", location: "/new/photos/p1"},
+		{name: "site home", host: "example.com", path: "/old", location: "/new"},
+		{name: "api", host: "example.com", path: "/api/v1/public/portfolios/old", location: "/api/v1/public/portfolios/new"},
+		{name: "custom domain site", host: "photos.example.com", path: "/photos/p1", location: "/photos/p1"},
+		{name: "custom domain home", host: "photos.example.com", path: "/", location: "/"},
+		{name: "custom domain api", host: "photos.example.com", path: "/api/v1/public/portfolio", location: "/api/v1/public/portfolio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusFound {
+				t.Fatalf("got status %d, want %d", rec.Code, http.StatusFound)
+			}
+
+			if got := rec.Header().Get(echo.HeaderLocation); got != tt.location {
+				t.Errorf("got location %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const themePlansPageSize = 100
+
+// findThemesHandler lists the theme registry with the plans allowing each
+// theme.
+func (h *httpService) findThemesHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	plans, _, err := h.membershipPlanRepo.FindAll(c.Request().Context(), model.MembershipPlanQueryInput{
+		PaginatedRequest: model.PaginatedRequest{Size: themePlansPageSize},
+	})
+	if err != nil {
+		logger.WithError(err).Error("failed to find membership plans")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	plan, err := h.activePlan(c.Request().Context(), session.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.WithError(err).Error("failed to find active plan")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	var listings []model.ThemeListing
+
+	for _, theme := range model.Themes() {
+		listing := model.ThemeListing{
+			Theme:   theme,
+			Plans:   []string{},
+			Allowed: plan.AllowsTheme(theme),
+		}
+
+		for _, p := range plans {
+			if p.AllowsTheme(theme) {
+				listing.Plans = append(listing.Plans, p.ID)
+			}
+		}
+
+		listings = append(listings, listing)
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: listings})
+}
+
+// validatePortfolioTheme checks a theme change and its overrides against the
+// registry and the plan of the portfolio's owner, who is not the caller when
+// an admin patches. Overrides sent alone are checked against the current
+// theme.
+func (h *httpService) validatePortfolioTheme(ctx context.Context, input model.Portfolio) (int, error) {
+	var current model.Portfolio
+
+	// Patch only applies themes to an identified portfolio, so without an ID
+	// there is no owner or current theme to check against.
+	if input.ID != "" {
+		var err error
+
+		current, err = h.portfolioRepo.FindByID(ctx, input.ID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	name := input.Theme
+	if name == "" {
+		name = current.Theme
+	}
+
+	if name == "" && input.ID == "" {
+		return 0, nil
+	}
+
+	theme, ok := model.FindTheme(name)
+	if !ok {
+		return http.StatusBadRequest, model.ErrUnknownTheme
+	}
+
+	if err := theme.Validate(input.ThemeSettings); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if input.ID == "" || input.Theme == "" || !theme.Premium {
+		return 0, nil
+	}
+
+	plan, err := h.activePlan(ctx, current.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if !plan.AllowsTheme(theme) {
+		return http.StatusForbidden, model.ErrThemeNotAllowed
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,269 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const tusUploadExpiry = 24 * time.Hour
+
+func (h *httpService) tusOptionsHandler(c echo.Context) error {
+	c.Response().Header().Set("Tus-Resumable", model.TusVersion)
+	c.Response().Header().Set("Tus-Version", model.TusVersion)
+	c.Response().Header().Set("Tus-Extension", "creation,termination,expiration")
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *httpService) tusCreateHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	if !checkTusResumable(c) {
+		return c.JSON(http.StatusPreconditionFailed, response{Message: "unsupported tus version"})
+	}
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	length, err := strconv.ParseInt(c.Request().Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		return c.JSON(http.StatusBadRequest, response{Message: "invalid Upload-Length"})
+	}
+
+	metadata, err := parseTusMetadata(c.Request().Header.Get("Upload-Metadata"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	photo := model.UploadSession{Metadata: metadata}.Photo()
+
+	if err := h.checkPhotoOwnership(c.Request().Context(), session.ID, photo); err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	limits, err := h.uploadLimits(c.Request().Context(), session.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get upload limits")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	if limits.MaxBytes > 0 && length > limits.MaxBytes {
+		return uploadErrorResponse(c, fmt.Errorf("%w: %d bytes exceeds %d", model.ErrFileTooLarge, length, limits.MaxBytes))
+	}
+
+	if err := h.checkQuota(c.Request().Context(), session.ID, photo.ID, length); err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	upload := model.UploadSession{
+		ID:        ulid.Make().String(),
+		UserID:    session.ID,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(tusUploadExpiry),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := h.uploadSessionRepo.Create(c.Request().Context(), upload); err != nil {
+		logger.WithError(err).Error("failed to create upload session")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	setTusHeaders(c, upload)
+	c.Response().Header().Set(echo.HeaderLocation, c.Request().URL.Path+"/"+upload.ID)
+
+	return c.NoContent(http.StatusCreated)
+}
+
+func (h *httpService) tusHeadHandler(c echo.Context) error {
+	upload, status, err := h.findUploadSession(c)
+	if err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	setTusHeaders(c, upload)
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *httpService) tusPatchHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	upload, status, err := h.findUploadSession(c)
+	if err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	if c.Request().Header.Get(echo.HeaderContentType) != "application/offset+octet-stream" {
+		return c.JSON(http.StatusUnsupportedMediaType, response{Message: "invalid Content-Type"})
+	}
+
+	offset, err := strconv.ParseInt(c.Request().Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		return c.JSON(http.StatusConflict, response{Message: model.ErrUploadOffsetMismatch.Error()})
+	}
+
+	if upload.CompletedAt.Valid || time.Now().After(upload.ExpiresAt) {
+		return c.JSON(http.StatusGone, response{Message: "upload is no longer available"})
+	}
+
+	upload.Offset, err = h.uploadSessionRepo.Append(c.Request().Context(), upload, c.Request().Body, time.Now().Add(tusUploadExpiry))
+	if errors.Is(err, model.ErrUploadOffsetMismatch) {
+		return c.JSON(http.StatusConflict, response{Message: err.Error()})
+	}
+
+	// Bytes that arrived before a dropped connection are kept, so only a
+	// chunk that stored nothing fails the request.
+	if err != nil && upload.Offset == offset {
+		logger.WithError(err).Error("failed to append upload chunk")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	upload.ExpiresAt = time.Now().Add(tusUploadExpiry)
+
+	if upload.IsComplete() {
+		photo, err := h.finishUploadSession(c.Request().Context(), upload)
+		if err != nil {
+			logger.WithError(err).Error("failed to finish upload")
+			return uploadErrorResponse(c, err)
+		}
+
+		c.Response().Header().Set("Upload-Photo-ID", photo.ID)
+	}
+
+	setTusHeaders(c, upload)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *httpService) tusDeleteHandler(c echo.Context) error {
+	upload, status, err := h.findUploadSession(c)
+	if err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	if err := h.uploadSessionRepo.Delete(c.Request().Context(), upload.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	c.Response().Header().Set("Tus-Resumable", model.TusVersion)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// finishUploadSession runs a completed upload through the regular photo path.
+// Rejected uploads are discarded since resuming them cannot succeed; other
+// failures keep the session so a repeated PATCH can finish it.
+func (h *httpService) finishUploadSession(ctx context.Context, upload model.UploadSession) (model.Photo, error) {
+	buf, err := h.uploadSessionRepo.ReadAll(ctx, upload.ID)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	limits, err := h.uploadLimits(ctx, upload.UserID)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	photo, err := h.storePhoto(ctx, upload.UserID, upload.Photo(), buf, limits, h.uploaderRepo.SavePhoto)
+	if isUploadRejection(err) {
+		h.uploadSessionRepo.Delete(ctx, upload.ID)
+	}
+
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	if err := h.uploadSessionRepo.Complete(ctx, upload.ID, photo.ID); err != nil {
+		return model.Photo{}, err
+	}
+
+	return photo, nil
+}
+
+// findUploadSession loads the caller's session named in the path, returning
+// the status to respond with on error.
+func (h *httpService) findUploadSession(c echo.Context) (model.UploadSession, int, error) {
+	if !checkTusResumable(c) {
+		return model.UploadSession{}, http.StatusPreconditionFailed, errors.New("unsupported tus version")
+	}
+
+	session, err := authSession(c)
+	if err != nil {
+		return model.UploadSession{}, http.StatusUnauthorized, errors.New("unauthorized")
+	}
+
+	upload, err := h.uploadSessionRepo.FindByID(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.UploadSession{}, http.StatusNotFound, errors.New("upload not found")
+	}
+
+	if err != nil {
+		return model.UploadSession{}, http.StatusInternalServerError, err
+	}
+
+	if upload.UserID != session.ID {
+		return model.UploadSession{}, http.StatusForbidden, model.ErrForbidden
+	}
+
+	return upload, 0, nil
+}
+
+// checkTusResumable reports whether the client speaks the supported tus
+// version.
+func checkTusResumable(c echo.Context) bool {
+	if c.Request().Header.Get("Tus-Resumable") != model.TusVersion {
+		c.Response().Header().Set("Tus-Version", model.TusVersion)
+		return false
+	}
+
+	return true
+}
+
+func setTusHeaders(c echo.Context, upload model.UploadSession) {
+	header := c.Response().Header()
+	header.Set("Tus-Resumable", model.TusVersion)
+	header.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	header.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseTusMetadata decodes "key base64value,key2 base64value2".
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q", key)
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
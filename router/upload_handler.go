@@ -1,14 +1,24 @@
 package router
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/oklog/ulid/v2"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 func (h *httpService) uploadPhotoHandler(c echo.Context) error {
@@ -27,6 +37,41 @@ func (h *httpService) uploadPhotoHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
 	}
 
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: err.Error()})
+	}
+
+	if err := h.checkPhotoOwnership(c.Request().Context(), session.ID, photo); err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	if isVideoFile(file) {
+		return h.uploadVideo(c, session.ID, photo, file, h.uploaderRepo.SavePhoto)
+	}
+
+	return h.uploadImage(c, session.ID, photo, file, h.uploaderRepo.SavePhoto)
+}
+
+// photoSaver persists a stored photo: SavePhoto for new photos and
+// ReplacePhoto when the file of an existing one is swapped.
+type photoSaver func(ctx context.Context, photo model.Photo) error
+
+// uploadImage stores an image sent to an upload endpoint.
+func (h *httpService) uploadImage(c echo.Context, userID string, photo model.Photo, file *multipart.FileHeader, save photoSaver) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	limits, err := h.uploadLimits(c.Request().Context(), userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get upload limits")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	if limits.MaxBytes > 0 && file.Size > limits.MaxBytes {
+		return uploadErrorResponse(c, fmt.Errorf("%w: %d bytes exceeds %d", model.ErrFileTooLarge, file.Size, limits.MaxBytes))
+	}
+
 	src, err := file.Open()
 	if err != nil {
 		logger.WithError(err).Error("failed to open file")
@@ -34,40 +79,405 @@ func (h *httpService) uploadPhotoHandler(c echo.Context) error {
 	}
 	defer src.Close()
 
+	buf, err := io.ReadAll(src)
+	if err != nil {
+		logger.WithError(err).Error("failed to read file")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	newPhoto, err := h.storePhoto(c.Request().Context(), userID, photo, buf, limits, save)
+	if err != nil {
+		logger.WithError(err).Error("failed to store photo")
+		return uploadErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response{
+		Success: true,
+		Data:    newPhoto,
+	})
+}
+
+// storePhoto validates an image, uploads it with its variants and saves the
+// photo row with save.
+func (h *httpService) storePhoto(ctx context.Context, userID string, photo model.Photo, buf []byte, limits imaging.Limits, save photoSaver) (model.Photo, error) {
+	if _, err := imaging.Validate(buf, limits); err != nil {
+		return model.Photo{}, err
+	}
+
+	hash, err := imaging.DHash(buf)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to hash photo")
+	}
+
+	placeholder, err := imaging.NewPlaceholder(buf)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to compute photo placeholder")
+	}
+
+	duplicates, err := h.findDuplicates(ctx, userID, photo.ID, hash)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	if len(duplicates) > 0 && h.duplicatePolicy == model.DuplicatePolicyBlock {
+		return model.Photo{}, fmt.Errorf("%w: %s", model.ErrDuplicatePhoto, strings.Join(duplicates, ", "))
+	}
+
+	portfolio, err := h.portfolioRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	metadataSettings := model.NewDefaultMetadataSettings()
+	if portfolio.MetadataSettings != nil {
+		metadataSettings = portfolio.MetadataSettings
+	}
+
+	metadata := imaging.ExtractMetadata(buf)
+
+	buf, err = imaging.StripPrivateMetadata(buf, metadataSettings.KeepLocation)
+	if err != nil {
+		return model.Photo{}, fmt.Errorf("%w: %s", model.ErrCorruptImage, err.Error())
+	}
+
+	folder := uploadPath(userID)
+
+	// The original is unwatermarked, so only the asset route delivers it.
+	url, publicID, err := h.uploaderRepo.UploadPrivate(ctx, bytes.NewReader(buf), folder)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	if photo.ID == "" {
+		photo.ID = ulid.Make().String()
+	}
+
+	variants, err := h.variantRenderer.Render(ctx, buf, folder, photo.ID, portfolio.WatermarkSettings)
+	if err != nil {
+		h.assetDeletionRepo.Enqueue(ctx, []string{publicID})
+		return model.Photo{}, err
+	}
+
+	// Variants are charged with the original, so the quota is only known
+	// once they are rendered.
+	if err := h.checkQuota(ctx, userID, photo.ID, int64(len(buf))+variantBytes(variants)); err != nil {
+		h.assetDeletionRepo.Enqueue(ctx, append(variantPublicIDs(variants), publicID))
+		return model.Photo{}, err
+	}
+
+	newPhoto := model.Photo{
+		ID:            photo.ID,
+		UserID:        userID,
+		FolderID:      photo.FolderID,
+		Src:           model.PublicSrc(url, variants),
+		OriginalSrc:   url,
+		PublicID:      publicID,
+		Alt:           photo.Alt,
+		Caption:       photo.Caption,
+		SortIndex:     photo.SortIndex,
+		Metadata:      metadata,
+		MediaType:     model.MediaTypeImage,
+		PHash:         hash,
+		BlurHash:      placeholder.BlurHash,
+		DominantColor: placeholder.DominantColor,
+		AverageColor:  placeholder.AverageColor,
+		Bytes:         int64(len(buf)) + variantBytes(variants),
+		CreatedAt:     createdAt(photo),
+		Variants:      variants,
+	}
+
+	if err := save(ctx, newPhoto); err != nil {
+		h.assetDeletionRepo.Enqueue(ctx, append(variantPublicIDs(variants), publicID))
+		return model.Photo{}, err
+	}
+
+	h.feedCache.invalidateUser(userID)
+
+	newPhoto.DuplicateOf = duplicates
+
+	return newPhoto, nil
+}
+
+const signedUploadExpiry = 15 * time.Minute
+
+func (h *httpService) signUploadHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
 	session, err := authSession(c)
 	if err != nil {
 		logger.WithError(err).Error("failed to get session")
-		return c.JSON(http.StatusUnauthorized, response{Message: err.Error()})
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
 	}
 
-	path := fmt.Sprintf("%s/%s/%s", os.Getenv("UPLOADER_BASE_PATH"), "portfolios", session.ID)
+	signed, err := h.uploaderRepo.SignUpload(c.Request().Context(), strings.Trim(uploadPath(session.ID), "/"), signedUploadExpiry)
+	if errors.Is(err, model.ErrDirectUploadUnsupported) {
+		return c.JSON(http.StatusNotImplemented, response{Message: err.Error()})
+	}
 
-	url, publicID, err := h.uploaderRepo.Upload(c.Request().Context(), src, path)
 	if err != nil {
-		logger.WithError(err).Error("failed to upload file")
+		logger.WithError(err).Error("failed to sign upload")
 		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
 	}
 
-	newPhoto := model.Photo{
-		ID:        photo.ID,
-		Src:       url,
-		PublicID:  publicID,
-		Alt:       photo.Alt,
-		Caption:   photo.Caption,
-		SortIndex: photo.SortIndex,
-		CreatedAt: time.Now(),
+	return c.JSON(http.StatusOK, response{Success: true, Data: signed})
+}
+
+func (h *httpService) confirmUploadHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	var req model.ConfirmUploadRequest
+
+	if err := c.Bind(&req); err != nil {
+		logger.WithError(err).Error("failed to bind request")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	if !ownsPublicID(session.ID, req.PublicID) {
+		return c.JSON(http.StatusForbidden, response{Message: "asset is outside of your upload folder"})
+	}
+
+	if err := h.checkPhotoOwnership(c.Request().Context(), session.ID, model.Photo{ID: req.ID, FolderID: req.FolderID}); err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	asset, err := h.uploaderRepo.FindAsset(c.Request().Context(), req.PublicID)
+	if errors.Is(err, model.ErrAssetNotFound) {
+		return c.JSON(http.StatusNotFound, response{Message: err.Error()})
 	}
 
-	err = h.uploaderRepo.SavePhoto(c.Request().Context(), newPhoto)
 	if err != nil {
-		logger.WithError(err).Error("failed to save photo")
+		logger.WithError(err).Error("failed to find asset")
 		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, response{
-		Success: true,
-		Data:    newPhoto,
-	})
+	limits, err := h.uploadLimits(c.Request().Context(), session.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get upload limits")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	// The direct upload is only a staging copy: it is read back through the
+	// same pipeline as a multipart upload, which stores a stripped copy, and
+	// is deleted whatever the outcome.
+	defer h.assetDeletionRepo.Enqueue(c.Request().Context(), []string{asset.PublicID})
+
+	if err := checkAssetLimits(asset, limits); err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	buf, err := h.readAsset(c.Request().Context(), asset.PublicID, limits)
+	if err != nil {
+		logger.WithError(err).Error("failed to read asset")
+		return uploadErrorResponse(c, err)
+	}
+
+	photo := model.Photo{
+		ID:        req.ID,
+		FolderID:  req.FolderID,
+		Alt:       req.Alt,
+		Caption:   req.Caption,
+		SortIndex: req.SortIndex,
+	}
+
+	photo, err = h.storePhoto(c.Request().Context(), session.ID, photo, buf, limits, h.uploaderRepo.SavePhoto)
+	if err != nil {
+		logger.WithError(err).Error("failed to store photo")
+		return uploadErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: photo})
+}
+
+// readAsset downloads a stored file, refusing to read past the plan's upload
+// size.
+func (h *httpService) readAsset(ctx context.Context, publicID string, limits imaging.Limits) ([]byte, error) {
+	rc, err := h.uploaderRepo.Open(ctx, publicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	if limits.MaxBytes <= 0 {
+		return io.ReadAll(rc)
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(rc, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buf)) > limits.MaxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", model.ErrFileTooLarge, limits.MaxBytes)
+	}
+
+	return buf, nil
+}
+
+// checkAssetLimits applies plan limits to an asset uploaded without passing
+// through this server.
+func checkAssetLimits(asset model.Asset, limits imaging.Limits) error {
+	if limits.MaxBytes > 0 && asset.Bytes > limits.MaxBytes {
+		return fmt.Errorf("%w: %d bytes exceeds %d", model.ErrFileTooLarge, asset.Bytes, limits.MaxBytes)
+	}
+
+	if limits.MaxDimension > 0 && (asset.Width > limits.MaxDimension || asset.Height > limits.MaxDimension) {
+		return fmt.Errorf("%w: %dx%d exceeds %dpx", model.ErrImageTooLarge, asset.Width, asset.Height, limits.MaxDimension)
+	}
+
+	return nil
+}
+
+// uploadPath is the storage folder holding a user's portfolio assets.
+func uploadPath(userID string) string {
+	return fmt.Sprintf("%s/%s/%s", os.Getenv("UPLOADER_BASE_PATH"), "portfolios", userID)
+}
+
+// activePlan returns the plan of the user's active membership, falling back
+// to the free plan for users without one.
+func (h *httpService) activePlan(ctx context.Context, userID string) (model.MembershipPlan, error) {
+	membership, err := h.membershipRepo.FindActiveByUserID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return h.membershipPlanRepo.FindByID(ctx, os.Getenv("MEMBERSHIP_PLAN_FREE"))
+	}
+
+	if err != nil {
+		return model.MembershipPlan{}, err
+	}
+
+	return h.membershipPlanRepo.FindByID(ctx, membership.MembershipPlanID)
+}
+
+// uploadLimits returns the upload limits of the user's active membership plan.
+func (h *httpService) uploadLimits(ctx context.Context, userID string) (imaging.Limits, error) {
+	plan, err := h.activePlan(ctx, userID)
+	if err != nil {
+		return imaging.Limits{}, err
+	}
+
+	return imaging.Limits{
+		MaxBytes:     plan.MaxUploadBytes,
+		MaxDimension: plan.MaxImageDimension,
+	}, nil
+}
+
+// checkQuota rejects storing a photo of size bytes when it would go over the
+// plan's storage or photo limits. When photoID names an existing photo its
+// file is being replaced, so only the size difference counts.
+func (h *httpService) checkQuota(ctx context.Context, userID, photoID string, bytes int64) error {
+	plan, err := h.activePlan(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	usage, err := h.usageRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	photos := 1
+
+	if photoID != "" {
+		existing, err := h.uploaderRepo.FindByID(ctx, photoID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil {
+			bytes -= existing.Bytes
+			photos = 0
+		}
+	}
+
+	if plan.MaxStorageBytes > 0 && usage.Bytes+bytes > plan.MaxStorageBytes {
+		return fmt.Errorf("%w: %d of %d bytes used", model.ErrStorageQuotaExceeded, usage.Bytes, plan.MaxStorageBytes)
+	}
+
+	if plan.MaxPhotos > 0 && usage.Photos+photos > plan.MaxPhotos {
+		return fmt.Errorf("%w: %d of %d photos used", model.ErrPhotoLimitReached, usage.Photos, plan.MaxPhotos)
+	}
+
+	return nil
+}
+
+// uploadErrorResponse maps upload validation errors to structured 4xx codes.
+func uploadErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, model.ErrUnsupportedMediaType):
+		return c.JSON(http.StatusUnsupportedMediaType, response{Code: "unsupported_media_type", Message: err.Error()})
+	case errors.Is(err, model.ErrFileTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, response{Code: "file_too_large", Message: err.Error()})
+	case errors.Is(err, model.ErrImageTooLarge):
+		return c.JSON(http.StatusUnprocessableEntity, response{Code: "image_too_large", Message: err.Error()})
+	case errors.Is(err, model.ErrCorruptImage):
+		return c.JSON(http.StatusUnprocessableEntity, response{Code: "corrupt_image", Message: err.Error()})
+	case errors.Is(err, model.ErrCorruptVideo):
+		return c.JSON(http.StatusUnprocessableEntity, response{Code: "corrupt_video", Message: err.Error()})
+	case errors.Is(err, model.ErrVideoTooLong):
+		return c.JSON(http.StatusUnprocessableEntity, response{Code: "video_too_long", Message: err.Error()})
+	case errors.Is(err, model.ErrDuplicatePhoto):
+		return c.JSON(http.StatusConflict, response{Code: "duplicate_photo", Message: err.Error()})
+	case errors.Is(err, model.ErrStorageQuotaExceeded):
+		return c.JSON(http.StatusForbidden, response{Code: "storage_quota_exceeded", Message: err.Error()})
+	case errors.Is(err, model.ErrPhotoLimitReached):
+		return c.JSON(http.StatusForbidden, response{Code: "photo_limit_reached", Message: err.Error()})
+	case errors.Is(err, model.ErrForbidden):
+		return c.JSON(http.StatusForbidden, response{Code: "forbidden", Message: err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+}
+
+// isUploadRejection reports whether err rejects the file itself, so sending
+// it again cannot succeed.
+func isUploadRejection(err error) bool {
+	for _, target := range []error{
+		model.ErrUnsupportedMediaType,
+		model.ErrFileTooLarge,
+		model.ErrImageTooLarge,
+		model.ErrCorruptImage,
+		model.ErrCorruptVideo,
+		model.ErrVideoTooLong,
+		model.ErrDuplicatePhoto,
+		model.ErrStorageQuotaExceeded,
+		model.ErrPhotoLimitReached,
+		model.ErrForbidden,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func variantBytes(variants []model.PhotoVariant) int64 {
+	var total int64
+
+	for _, v := range variants {
+		total += v.Bytes
+	}
+
+	return total
+}
+
+func variantPublicIDs(variants []model.PhotoVariant) []string {
+	var publicIDs []string
+
+	for _, v := range variants {
+		publicIDs = append(publicIDs, v.PublicID)
+	}
+
+	return publicIDs
 }
 
 func (h *httpService) bulkRemovePhotosHandler(c echo.Context) error {
@@ -80,23 +490,208 @@ func (h *httpService) bulkRemovePhotosHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
 	}
 
-	err := h.uploaderRepo.DeleteByPublicIDs(c.Request().Context(), req.PublicIDs)
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	for _, publicID := range req.PublicIDs {
+		if session.Role != model.RoleAdmin && !ownsPublicID(session.ID, publicID) {
+			return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+		}
+	}
+
+	if status, err := h.authorize(c.Request().Context(), session, model.ResourceAsset, req.PublicIDs...); err != nil {
+		return c.JSON(status, response{Message: err.Error()})
+	}
+
+	// Variants and posters go along with their original.
+	photos, err := h.uploaderRepo.FindByPublicIDs(c.Request().Context(), req.PublicIDs)
+	if err != nil {
+		logger.WithError(err).Error("failed to find photos")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	publicIDs := append([]string{}, req.PublicIDs...)
+	for _, photo := range photos {
+		publicIDs = append(publicIDs, variantPublicIDs(photo.Variants)...)
+
+		if photo.PosterPublicID != "" {
+			publicIDs = append(publicIDs, photo.PosterPublicID)
+		}
+	}
+
+	err = h.uploaderRepo.DeleteByPublicIDs(c.Request().Context(), publicIDs)
 	if err != nil {
 		logger.WithError(err).Error("failed to delete files")
 		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
 	}
 
+	h.feedCache.invalidateUser(session.ID)
+
 	return c.JSON(http.StatusOK, response{Success: true})
 }
 
-func (h *httpService) flushHandler(c echo.Context) error {
+// purgeHandler deletes every upload under a user's prefix. A dry run returns
+// the counts and the phrase to send back as confirm.
+func (h *httpService) purgeHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	var req model.PurgeRequest
+
+	if err := c.Bind(&req); err != nil {
+		logger.WithError(err).Error("failed to bind request")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	userID := session.ID
+	if req.UserID != "" && req.UserID != session.ID {
+		if session.Role != model.RoleAdmin {
+			return c.JSON(http.StatusForbidden, response{Message: "forbidden"})
+		}
+
+		userID = req.UserID
+	}
+
+	prefix := strings.Trim(uploadPath(userID), "/") + "/"
+
+	if req.DryRun {
+		report, err := h.uploadPurger.Preview(c.Request().Context(), prefix)
+		if err != nil {
+			logger.WithError(err).Error("failed to preview purge")
+			return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, response{Success: true, Data: report})
+	}
+
+	if req.Confirm != model.PurgeConfirmPhrase(prefix) {
+		return c.JSON(http.StatusBadRequest, response{
+			Code:    "confirmation_required",
+			Message: fmt.Sprintf("confirm must be %q", model.PurgeConfirmPhrase(prefix)),
+		})
+	}
+
+	report, err := h.uploadPurger.Purge(c.Request().Context(), session.ID, prefix)
+	if err != nil {
+		logger.WithError(err).Error("failed to purge uploads")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	h.feedCache.invalidateUser(userID)
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: report})
+}
+
+// assetHandler redirects to a stored asset, or streams a private photo
+// original. Visitors only get assets that a published portfolio delivers;
+// signed-in users also get their own keys, such as photos of an unpublished
+// portfolio.
+func (h *httpService) assetHandler(c echo.Context) error {
 	logger := logrus.WithContext(c.Request().Context())
 
-	err := h.uploaderRepo.Flush(c.Request().Context())
+	publicID := c.Param("*")
+
+	if session, err := authSession(c); err != nil || (session.Role != model.RoleAdmin && !ownsPublicID(session.ID, publicID)) {
+		published, err := h.uploaderRepo.IsPublishedAsset(c.Request().Context(), publicID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+		}
+
+		if !published {
+			return c.JSON(http.StatusNotFound, response{Message: "asset not found"})
+		}
+	}
+
+	// Originals of watermarked photos are only delivered through variants.
+	photos, err := h.uploaderRepo.FindByPublicIDs(c.Request().Context(), []string{publicID})
 	if err != nil {
-		logger.WithError(err).Error("failed to flush files")
 		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, response{Success: true})
+	if len(photos) > 0 && photos[0].OriginalSrc != "" && photos[0].Src != photos[0].OriginalSrc {
+		return c.JSON(http.StatusNotFound, response{Message: "asset not found"})
+	}
+
+	if len(photos) > 0 && photos[0].IsVideo() {
+		return c.Redirect(http.StatusFound, photos[0].Src)
+	}
+
+	// Image originals are stored privately, so they are streamed rather than
+	// redirected to storage.
+	if len(photos) > 0 {
+		rc, err := h.uploaderRepo.Open(c.Request().Context(), publicID)
+		if errors.Is(err, model.ErrAssetNotFound) {
+			return c.JSON(http.StatusNotFound, response{Message: "asset not found"})
+		}
+
+		if err != nil {
+			logger.WithError(err).Error("failed to open asset")
+			return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+		}
+		defer rc.Close()
+
+		reader := bufio.NewReader(rc)
+		head, _ := reader.Peek(512)
+
+		return c.Stream(http.StatusOK, http.DetectContentType(head), reader)
+	}
+
+	url, err := h.uploaderRepo.URL(c.Request().Context(), publicID)
+	if err != nil {
+		logger.WithError(err).Error("failed to resolve asset url")
+		return c.JSON(http.StatusNotFound, response{Message: "asset not found"})
+	}
+
+	return c.Redirect(http.StatusFound, url)
+}
+
+const watermarkLogoMaxBytes = 5 << 20
+
+// uploadWatermarkLogoHandler stores a logo for the portfolio watermark. The
+// returned public ID is saved through the portfolio watermark settings.
+func (h *httpService) uploadWatermarkLogoHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		logger.WithError(err).Error("failed to get file")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+	defer src.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(src, watermarkLogoMaxBytes+1))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	if _, err := imaging.Validate(buf, imaging.Limits{MaxBytes: watermarkLogoMaxBytes, MaxDimension: 4000}); err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	url, publicID, err := h.uploaderRepo.Upload(c.Request().Context(), bytes.NewReader(buf), uploadPath(session.ID)+"/watermarks")
+	if err != nil {
+		logger.WithError(err).Error("failed to upload watermark logo")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: model.Asset{PublicID: publicID, URL: url}})
 }
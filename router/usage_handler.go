@@ -0,0 +1,33 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *httpService) usageHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	plan, err := h.activePlan(c.Request().Context(), session.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to find active plan")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	usage, err := h.usageRepo.FindByUserID(c.Request().Context(), session.ID)
+	if err != nil {
+		logger.WithError(err).Error("failed to find usage")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response{Success: true, Data: model.NewUsageSummary(usage, plan)})
+}
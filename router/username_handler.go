@@ -0,0 +1,51 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *httpService) changeUsernameHandler(c echo.Context) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	session, err := authSession(c)
+	if err != nil {
+		logger.WithError(err).Error("failed to get session")
+		return c.JSON(http.StatusUnauthorized, response{Message: "unauthorized"})
+	}
+
+	var req model.ChangeUsernameRequest
+
+	if err := c.Bind(&req); err != nil {
+		logger.WithError(err).Error("failed to bind request")
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	username := model.NormalizeUsername(req.Username)
+
+	if err := model.ValidateUsername(username); err != nil {
+		return c.JSON(http.StatusBadRequest, response{Message: err.Error()})
+	}
+
+	user, err := h.usernameRepo.Change(c.Request().Context(), session.ID, username)
+	if errors.Is(err, model.ErrUsernameTaken) {
+		return c.JSON(http.StatusConflict, response{Message: err.Error()})
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("failed to change username")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	// Cached documents of the old name must give way to its redirect.
+	h.feedCache.invalidateUser(session.ID)
+
+	return c.JSON(http.StatusOK, response{
+		Success: true,
+		Data:    user,
+	})
+}
@@ -0,0 +1,171 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/notblessy/ekspresi-core/utils/imaging"
+	"github.com/notblessy/ekspresi-core/utils/video"
+	"github.com/oklog/ulid/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// isVideoFile reports whether an uploaded file is a supported clip.
+func isVideoFile(file *multipart.FileHeader) bool {
+	src, err := file.Open()
+	if err != nil {
+		return false
+	}
+	defer src.Close()
+
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(src, head)
+
+	return video.Sniff(head[:n]) != ""
+}
+
+// uploadVideo stores a clip sent to an upload endpoint. Clips are
+// spooled to disk since ffmpeg needs a seekable file.
+func (h *httpService) uploadVideo(c echo.Context, userID string, photo model.Photo, file *multipart.FileHeader, save photoSaver) error {
+	logger := logrus.WithContext(c.Request().Context())
+
+	limits, err := h.videoLimits(c.Request().Context(), userID)
+	if err != nil {
+		logger.WithError(err).Error("failed to get video limits")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+
+	if limits.MaxBytes > 0 && file.Size > limits.MaxBytes {
+		return uploadErrorResponse(c, fmt.Errorf("%w: %d bytes exceeds %d", model.ErrFileTooLarge, file.Size, limits.MaxBytes))
+	}
+
+	path, err := saveTempFile(file, "ekspresi-video-*")
+	if err != nil {
+		logger.WithError(err).Error("failed to store video")
+		return c.JSON(http.StatusInternalServerError, response{Message: err.Error()})
+	}
+	defer os.Remove(path)
+
+	newPhoto, err := h.storeVideo(c.Request().Context(), userID, photo, path, limits, save)
+	if err != nil {
+		logger.WithError(err).Error("failed to store video")
+		return uploadErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, response{
+		Success: true,
+		Data:    newPhoto,
+	})
+}
+
+// videoLimits returns the clip limits of the user's active membership plan.
+func (h *httpService) videoLimits(ctx context.Context, userID string) (video.Limits, error) {
+	plan, err := h.activePlan(ctx, userID)
+	if err != nil {
+		return video.Limits{}, err
+	}
+
+	return video.Limits{
+		MaxBytes:    plan.MaxVideoBytes,
+		MaxDuration: time.Duration(plan.MaxVideoSeconds) * time.Second,
+	}, nil
+}
+
+// storeVideo validates the clip at path, uploads it stripped of metadata with
+// a poster frame and saves the photo row with save.
+func (h *httpService) storeVideo(ctx context.Context, userID string, photo model.Photo, path string, limits video.Limits, save photoSaver) (model.Photo, error) {
+	info, err := video.Validate(ctx, path, limits)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	stripped, err := video.StripMetadata(ctx, path, info.Format)
+	if err != nil {
+		return model.Photo{}, fmt.Errorf("%w: %s", model.ErrCorruptVideo, err.Error())
+	}
+	defer os.Remove(stripped)
+
+	stat, err := os.Stat(stripped)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	poster, err := video.Poster(ctx, stripped, info.Duration)
+	if err != nil {
+		return model.Photo{}, fmt.Errorf("%w: %s", model.ErrCorruptVideo, err.Error())
+	}
+
+	// The poster is charged with the clip.
+	if err := h.checkQuota(ctx, userID, photo.ID, stat.Size()+int64(len(poster))); err != nil {
+		return model.Photo{}, err
+	}
+
+	placeholder, err := imaging.NewPlaceholder(poster)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to compute video placeholder")
+	}
+
+	folder := uploadPath(userID)
+
+	posterURL, posterID, err := h.uploaderRepo.Upload(ctx, bytes.NewReader(poster), folder)
+	if err != nil {
+		return model.Photo{}, err
+	}
+
+	file, err := os.Open(stripped)
+	if err != nil {
+		h.assetDeletionRepo.Enqueue(ctx, []string{posterID})
+		return model.Photo{}, err
+	}
+	defer file.Close()
+
+	url, publicID, err := h.uploaderRepo.UploadVideo(ctx, file, folder)
+	if err != nil {
+		h.assetDeletionRepo.Enqueue(ctx, []string{posterID})
+		return model.Photo{}, err
+	}
+
+	if photo.ID == "" {
+		photo.ID = ulid.Make().String()
+	}
+
+	newPhoto := model.Photo{
+		ID:             photo.ID,
+		UserID:         userID,
+		FolderID:       photo.FolderID,
+		Src:            url,
+		OriginalSrc:    url,
+		PublicID:       publicID,
+		MediaType:      model.MediaTypeVideo,
+		Width:          info.Width,
+		Height:         info.Height,
+		DurationMS:     info.Duration.Milliseconds(),
+		PosterSrc:      posterURL,
+		PosterPublicID: posterID,
+		Alt:            photo.Alt,
+		Caption:        photo.Caption,
+		SortIndex:      photo.SortIndex,
+		BlurHash:       placeholder.BlurHash,
+		DominantColor:  placeholder.DominantColor,
+		AverageColor:   placeholder.AverageColor,
+		Bytes:          stat.Size() + int64(len(poster)),
+		CreatedAt:      createdAt(photo),
+	}
+
+	if err := save(ctx, newPhoto); err != nil {
+		h.assetDeletionRepo.Enqueue(ctx, []string{publicID, posterID})
+		return model.Photo{}, err
+	}
+
+	h.feedCache.invalidateUser(userID)
+
+	return newPhoto, nil
+}
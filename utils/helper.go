@@ -1,12 +1,9 @@
 package utils
 
 import (
-	"bytes"
 	"encoding/json"
-	"io"
+	"os"
 	"strconv"
-
-	"github.com/h2non/bimg"
 )
 
 // Dump :nodoc:
@@ -15,16 +12,16 @@ func Dump(data interface{}) string {
 	return string(dataByte)
 }
 
+// GetEnv returns the environment variable or fallback when it is unset.
+func GetEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
 func ParseID(n string) int64 {
 	id, _ := strconv.ParseInt(n, 10, 64)
 	return id
 }
-
-func CompressImage(buffer []byte, quality int) (io.Reader, error) {
-	compressed, err := bimg.NewImage(buffer).Process(bimg.Options{Quality: quality})
-	if err != nil {
-		return nil, err
-	}
-
-	return bytes.NewReader(compressed), nil
-}
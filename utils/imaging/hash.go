@@ -0,0 +1,74 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/bits"
+	"strconv"
+
+	"github.com/h2non/bimg"
+)
+
+const DefaultDuplicateThreshold = 6
+
+// DHash computes a 64-bit difference hash of buf, returned as 16 hex digits.
+// The image is shrunk to 9x8 grey pixels and each bit records whether a pixel
+// is brighter than its right neighbour, so re-encodes and resizes of the same
+// picture land within a few bits of each other.
+func DHash(buf []byte) (string, error) {
+	thumb, err := bimg.NewImage(buf).Process(bimg.Options{
+		Width:          9,
+		Height:         8,
+		Force:          true,
+		Interpretation: bimg.InterpretationBW,
+		Type:           bimg.PNG,
+		StripMetadata:  true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	img, err := png.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		return "", err
+	}
+
+	var hash uint64
+
+	bounds := img.Bounds()
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+
+			if grey(img, bounds.Min.X+x, bounds.Min.Y+y) > grey(img, bounds.Min.X+x+1, bounds.Min.Y+y) {
+				hash |= 1
+			}
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+// HashDistance returns the number of differing bits between two hashes, or -1
+// when either is not a valid hash.
+func HashDistance(a, b string) int {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return -1
+	}
+
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return -1
+	}
+
+	return bits.OnesCount64(x ^ y)
+}
+
+func grey(img image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
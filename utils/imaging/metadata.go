@@ -0,0 +1,459 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"github.com/h2non/bimg"
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+const (
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagMakerNote        = 0x927C
+	tagBodySerialNumber = 0xA431
+	tagLensSerialNumber = 0xA435
+	tagCameraSerial     = 0xC62F
+)
+
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// ExtractMetadata reads EXIF and IPTC fields from buf. Missing or unreadable
+// fields are left empty.
+func ExtractMetadata(buf []byte) model.PhotoMetadata {
+	var metadata model.PhotoMetadata
+
+	x, err := exif.Decode(bytes.NewReader(buf))
+	if err == nil {
+		metadata.CameraMake = exifString(x, exif.Make)
+		metadata.CameraModel = exifString(x, exif.Model)
+		metadata.Lens = strings.TrimSpace(exifString(x, exif.LensMake) + " " + exifString(x, exif.LensModel))
+		metadata.FocalLength = exifFloat(x, exif.FocalLength)
+		metadata.Aperture = exifFloat(x, exif.FNumber)
+		metadata.Copyright = exifString(x, exif.Copyright)
+		metadata.Creator = exifString(x, exif.Artist)
+
+		if tag, err := x.Get(exif.ExposureTime); err == nil {
+			if num, den, err := tag.Rat2(0); err == nil && num > 0 {
+				metadata.ShutterSpeed = formatShutterSpeed(num, den)
+			}
+		}
+
+		if tag, err := x.Get(exif.ISOSpeedRatings); err == nil {
+			metadata.ISO, _ = tag.Int(0)
+		}
+
+		if capturedAt, err := x.DateTime(); err == nil {
+			metadata.CapturedAt = &capturedAt
+		}
+
+		if lat, long, err := x.LatLong(); err == nil {
+			metadata.Latitude = &lat
+			metadata.Longitude = &long
+		}
+	} else if meta, err := bimg.Metadata(buf); err == nil {
+		metadata.CameraMake = meta.EXIF.Make
+		metadata.CameraModel = meta.EXIF.Model
+		metadata.ISO = meta.EXIF.ISOSpeedRatings
+	}
+
+	iptc := readIPTC(buf)
+
+	if metadata.Creator == "" {
+		metadata.Creator = iptc.creator
+	}
+
+	if metadata.Copyright == "" {
+		metadata.Copyright = iptc.copyright
+	}
+
+	metadata.Keywords = iptc.keywords
+
+	return metadata
+}
+
+// StripPrivateMetadata removes serial numbers, XMP packets and, unless
+// keepLocation is set, GPS data. XMP is dropped whole since it can repeat
+// both. JPEG, PNG and WebP containers are edited so every other tag survives;
+// HEIC and AVIF lose all metadata, whatever keepLocation says, since their
+// boxes cannot be edited in place.
+func StripPrivateMetadata(buf []byte, keepLocation bool) ([]byte, error) {
+	switch Sniff(buf) {
+	case FormatJPEG:
+		return stripJPEG(buf, keepLocation)
+	case FormatPNG:
+		return stripPNG(buf, keepLocation)
+	case FormatWebP:
+		return stripWebP(buf, keepLocation)
+	default:
+		return bimg.NewImage(buf).Process(bimg.Options{StripMetadata: true})
+	}
+}
+
+var (
+	xmpNamespace         = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	extendedXMPNamespace = []byte("http://ns.adobe.com/xmp/extension/\x00")
+)
+
+// stripJPEG edits the Exif APP1 segments in place and leaves out the XMP
+// ones.
+func stripJPEG(buf []byte, keepLocation bool) ([]byte, error) {
+	stripped := make([]byte, 0, len(buf))
+	stripped = append(stripped, buf[:2]...)
+
+	i := 2
+	for i+4 <= len(buf) && buf[i] == 0xFF {
+		m := buf[i+1]
+		if m == 0xDA || m == 0xD9 {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(buf[i+2:]))
+		if length < 2 || i+2+length > len(buf) {
+			return nil, fmt.Errorf("jpeg: segment out of range")
+		}
+
+		segment := append([]byte{}, buf[i:i+2+length]...)
+		payload := segment[4:]
+
+		if m == 0xE1 {
+			if bytes.HasPrefix(payload, xmpNamespace) || bytes.HasPrefix(payload, extendedXMPNamespace) {
+				i += 2 + length
+				continue
+			}
+
+			if bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				if err := stripTIFF(payload[6:], keepLocation); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		stripped = append(stripped, segment...)
+		i += 2 + length
+	}
+
+	return append(stripped, buf[i:]...), nil
+}
+
+// stripPNG edits the eXIf chunk in place and leaves out text chunks holding
+// XMP or raw metadata profiles.
+func stripPNG(buf []byte, keepLocation bool) ([]byte, error) {
+	stripped := append([]byte{}, buf[:8]...)
+
+	for i := 8; i < len(buf); {
+		if i+12 > len(buf) {
+			return nil, fmt.Errorf("png: chunk out of range")
+		}
+
+		length := int(binary.BigEndian.Uint32(buf[i:]))
+		if length < 0 || i+12+length > len(buf) {
+			return nil, fmt.Errorf("png: chunk out of range")
+		}
+
+		chunk := append([]byte{}, buf[i:i+12+length]...)
+		kind := string(chunk[4:8])
+		data := chunk[8 : 8+length]
+
+		switch kind {
+		case "iTXt", "tEXt", "zTXt":
+			keyword, _, _ := bytes.Cut(data, []byte{0})
+			if string(keyword) == "XML:com.adobe.xmp" || bytes.HasPrefix(keyword, []byte("Raw profile type")) {
+				i += 12 + length
+				continue
+			}
+		case "eXIf":
+			if err := stripTIFF(data, keepLocation); err != nil {
+				return nil, err
+			}
+
+			binary.BigEndian.PutUint32(chunk[8+length:], crc32.ChecksumIEEE(chunk[4:8+length]))
+		}
+
+		stripped = append(stripped, chunk...)
+		i += 12 + length
+
+		if kind == "IEND" {
+			break
+		}
+	}
+
+	return stripped, nil
+}
+
+// webpXMPFlag marks an XMP chunk in the VP8X header.
+const webpXMPFlag = 0x04
+
+// stripWebP edits the EXIF chunk in place, leaves out the XMP one and fixes
+// up the RIFF size and VP8X flags.
+func stripWebP(buf []byte, keepLocation bool) ([]byte, error) {
+	stripped := append([]byte{}, buf[:12]...)
+
+	for i := 12; i < len(buf); {
+		if i+8 > len(buf) {
+			return nil, fmt.Errorf("webp: chunk out of range")
+		}
+
+		size := int(binary.LittleEndian.Uint32(buf[i+4:]))
+		padded := size + size%2
+		if size < 0 || i+8+padded > len(buf) {
+			return nil, fmt.Errorf("webp: chunk out of range")
+		}
+
+		chunk := append([]byte{}, buf[i:i+8+padded]...)
+		data := chunk[8 : 8+size]
+
+		switch string(chunk[:4]) {
+		case "XMP ":
+			i += 8 + padded
+			continue
+		case "VP8X":
+			if len(data) > 0 {
+				data[0] &^= webpXMPFlag
+			}
+		case "EXIF":
+			if err := stripTIFF(bytes.TrimPrefix(data, []byte("Exif\x00\x00")), keepLocation); err != nil {
+				return nil, err
+			}
+		}
+
+		stripped = append(stripped, chunk...)
+		i += 8 + padded
+	}
+
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+
+	return stripped, nil
+}
+
+func stripTIFF(tiff []byte, keepLocation bool) error {
+	if len(tiff) < 8 {
+		return fmt.Errorf("exif: short tiff header")
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("exif: invalid byte order")
+	}
+
+	ifd0 := order.Uint32(tiff[4:8])
+
+	return walkIFD(tiff, order, ifd0, func(entry []byte, tag uint16) error {
+		switch tag {
+		case tagCameraSerial:
+			zeroValue(tiff, order, entry)
+		case tagGPSIFD:
+			if !keepLocation {
+				clearIFD(tiff, order, order.Uint32(entry[8:12]))
+			}
+		case tagExifIFD:
+			return walkIFD(tiff, order, order.Uint32(entry[8:12]), func(entry []byte, tag uint16) error {
+				switch tag {
+				case tagBodySerialNumber, tagLensSerialNumber, tagMakerNote:
+					zeroValue(tiff, order, entry)
+				}
+
+				return nil
+			})
+		}
+
+		return nil
+	})
+}
+
+func walkIFD(tiff []byte, order binary.ByteOrder, offset uint32, fn func(entry []byte, tag uint16) error) error {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return fmt.Errorf("exif: ifd out of range")
+	}
+
+	count := uint32(order.Uint16(tiff[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(tiff)) {
+		return fmt.Errorf("exif: ifd entries out of range")
+	}
+
+	for i := uint32(0); i < count; i++ {
+		entry := tiff[offset+2+i*12 : offset+14+i*12]
+		if err := fn(entry, order.Uint16(entry)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// zeroValue blanks the value of an IFD entry while keeping the entry valid.
+func zeroValue(tiff []byte, order binary.ByteOrder, entry []byte) {
+	size := tiffTypeSizes[order.Uint16(entry[2:4])] * order.Uint32(entry[4:8])
+	if size <= 4 {
+		clear(entry[8:12])
+		return
+	}
+
+	offset := order.Uint32(entry[8:12])
+	if uint64(offset)+uint64(size) <= uint64(len(tiff)) {
+		clear(tiff[offset : offset+size])
+	}
+}
+
+// clearIFD blanks every entry of an IFD and marks it empty.
+func clearIFD(tiff []byte, order binary.ByteOrder, offset uint32) {
+	walkIFD(tiff, order, offset, func(entry []byte, tag uint16) error {
+		zeroValue(tiff, order, entry)
+		clear(entry)
+		return nil
+	})
+
+	if uint64(offset)+2 <= uint64(len(tiff)) {
+		order.PutUint16(tiff[offset:], 0)
+	}
+}
+
+// jpegSegments returns the payloads of every marker segment before the scan
+// data. The returned slices alias buf.
+func jpegSegments(buf []byte, marker byte) [][]byte {
+	var segments [][]byte
+
+	for i := 2; i+4 <= len(buf) && buf[i] == 0xFF; {
+		m := buf[i+1]
+		if m == 0xDA || m == 0xD9 {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(buf[i+2:]))
+		if length < 2 || i+2+length > len(buf) {
+			break
+		}
+
+		if m == marker {
+			segments = append(segments, buf[i+4:i+2+length])
+		}
+
+		i += 2 + length
+	}
+
+	return segments
+}
+
+type iptcRecord struct {
+	creator   string
+	copyright string
+	keywords  []string
+}
+
+// readIPTC reads the IPTC-NAA block stored in a JPEG Photoshop segment.
+func readIPTC(buf []byte) iptcRecord {
+	var record iptcRecord
+
+	if bimg.DetermineImageType(buf) != bimg.JPEG {
+		return record
+	}
+
+	for _, segment := range jpegSegments(buf, 0xED) {
+		data, ok := bytes.CutPrefix(segment, []byte("Photoshop 3.0\x00"))
+		if !ok {
+			continue
+		}
+
+		for len(data) >= 12 && bytes.HasPrefix(data, []byte("8BIM")) {
+			id := binary.BigEndian.Uint16(data[4:6])
+
+			nameLength := int(data[6]) + 1
+			nameLength += nameLength % 2
+
+			if 6+nameLength+4 > len(data) {
+				break
+			}
+
+			size := int(binary.BigEndian.Uint32(data[6+nameLength:]))
+			start := 6 + nameLength + 4
+
+			if start+size > len(data) {
+				break
+			}
+
+			if id == 0x0404 {
+				parseIPTC(data[start:start+size], &record)
+			}
+
+			data = data[start+size+size%2:]
+		}
+	}
+
+	return record
+}
+
+func parseIPTC(data []byte, record *iptcRecord) {
+	for len(data) >= 5 && data[0] == 0x1C {
+		recordNumber, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:5]))
+
+		if 5+size > len(data) {
+			return
+		}
+
+		value := strings.TrimSpace(string(data[5 : 5+size]))
+
+		if recordNumber == 2 {
+			switch dataset {
+			case 25:
+				record.keywords = append(record.keywords, value)
+			case 80:
+				record.creator = value
+			case 116:
+				record.copyright = value
+			}
+		}
+
+		data = data[5+size:]
+	}
+}
+
+func exifString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+
+	value, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimRight(value, "\x00"))
+}
+
+func exifFloat(x *exif.Exif, name exif.FieldName) float64 {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+
+	rat, err := tag.Rat(0)
+	if err != nil {
+		return 0
+	}
+
+	value, _ := rat.Float64()
+	return value
+}
+
+func formatShutterSpeed(num, den int64) string {
+	if num >= den {
+		return fmt.Sprintf("%gs", float64(num)/float64(den))
+	}
+
+	return fmt.Sprintf("1/%d", (den+num/2)/num)
+}
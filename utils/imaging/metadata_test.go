@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// testTIFF is a little-endian EXIF block with a camera serial in IFD0 and a
+// GPS IFD holding one latitude entry.
+func testTIFF() []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+
+	ifd0 := make([]byte, 30)
+	binary.LittleEndian.PutUint16(ifd0, 2)
+	binary.LittleEndian.PutUint16(ifd0[2:], tagCameraSerial)
+	binary.LittleEndian.PutUint16(ifd0[4:], 2)
+	binary.LittleEndian.PutUint32(ifd0[6:], 4)
+	copy(ifd0[10:], "SN1\x00")
+	binary.LittleEndian.PutUint16(ifd0[14:], tagGPSIFD)
+	binary.LittleEndian.PutUint16(ifd0[16:], 4)
+	binary.LittleEndian.PutUint32(ifd0[18:], 1)
+	binary.LittleEndian.PutUint32(ifd0[22:], 38)
+
+	gps := make([]byte, 18)
+	binary.LittleEndian.PutUint16(gps, 1)
+	copy(gps[2:], testGPSEntry)
+
+	return append(append(tiff, ifd0...), gps...)
+}
+
+// testGPSEntry is a GPSLatitudeRef entry set to "N".
+var testGPSEntry = []byte{0x01, 0x00, 0x02, 0x00, 0x02, 0x00, 0x00, 0x00, 'N', 0x00, 0x00, 0x00}
+
+const testXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><exif:GPSLatitude>6,10N</exif:GPSLatitude><aux:SerialNumber>SN1</aux:SerialNumber></x:xmpmeta>`
+
+func testJPEG() []byte {
+	segment := func(marker byte, payload []byte) []byte {
+		s := []byte{0xFF, marker, 0, 0}
+		binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+		return append(s, payload...)
+	}
+
+	buf := []byte{0xFF, 0xD8}
+	buf = append(buf, segment(0xE1, append([]byte("Exif\x00\x00"), testTIFF()...))...)
+	buf = append(buf, segment(0xE1, append(append([]byte{}, xmpNamespace...), testXMP...))...)
+	buf = append(buf, segment(0xDA, []byte{1, 2, 3})...)
+
+	return append(buf, 0xFF, 0xD9)
+}
+
+func testPNG() []byte {
+	chunk := func(kind string, data []byte) []byte {
+		c := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		c = append(append(c, kind...), data...)
+		return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+	}
+
+	buf := []byte("\x89PNG\r\n\x1a\n")
+	buf = append(buf, chunk("IHDR", make([]byte, 13))...)
+	buf = append(buf, chunk("eXIf", testTIFF())...)
+	buf = append(buf, chunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"+testXMP))...)
+	buf = append(buf, chunk("IDAT", []byte{1, 2, 3})...)
+
+	return append(buf, chunk("IEND", nil)...)
+}
+
+func testWebP() []byte {
+	chunk := func(kind string, data []byte) []byte {
+		c := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+
+		return c
+	}
+
+	buf := []byte("RIFF\x00\x00\x00\x00WEBP")
+	buf = append(buf, chunk("VP8X", []byte{0x0C, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	buf = append(buf, chunk("VP8L", []byte{1, 2, 3})...)
+	buf = append(buf, chunk("EXIF", testTIFF())...)
+	buf = append(buf, chunk("XMP ", []byte(testXMP))...)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(buf)-8))
+
+	return buf
+}
+
+func TestStripPrivateMetadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		buf          []byte
+		keepLocation bool
+	}{
+		{name: "jpeg", buf: testJPEG()},
+		{name: "jpeg keeping location", buf: testJPEG(), keepLocation: true},
+		{name: "png", buf: testPNG()},
+		{name: "png keeping location", buf: testPNG(), keepLocation: true},
+		{name: "webp", buf: testWebP()},
+		{name: "webp keeping location", buf: testWebP(), keepLocation: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripPrivateMetadata(tt.buf, tt.keepLocation)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bytes.Contains(got, []byte("SN1")) {
+				t.Error("camera serial survived")
+			}
+
+			if bytes.Contains(got, []byte("xmpmeta")) {
+				t.Error("XMP packet survived")
+			}
+
+			if gps := bytes.Contains(got, testGPSEntry); gps != tt.keepLocation {
+				t.Errorf("got GPS kept %v, want %v", gps, tt.keepLocation)
+			}
+
+			if Sniff(got) != Sniff(tt.buf) {
+				t.Errorf("got format %q, want %q", Sniff(got), Sniff(tt.buf))
+			}
+		})
+	}
+}
+
+func TestStripWebPContainer(t *testing.T) {
+	got, err := StripPrivateMetadata(testWebP(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := binary.LittleEndian.Uint32(got[4:]); int(size) != len(got)-8 {
+		t.Errorf("got RIFF size %d, want %d", size, len(got)-8)
+	}
+
+	if got[20]&webpXMPFlag != 0 {
+		t.Error("VP8X still flags XMP")
+	}
+}
+
+func TestStripPNGChecksums(t *testing.T) {
+	got, err := StripPrivateMetadata(testPNG(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 8; i+12 <= len(got); {
+		length := int(binary.BigEndian.Uint32(got[i:]))
+		chunk := got[i : i+12+length]
+
+		if crc := binary.BigEndian.Uint32(chunk[8+length:]); crc != crc32.ChecksumIEEE(chunk[4:8+length]) {
+			t.Errorf("bad checksum on %s chunk", chunk[4:8])
+		}
+
+		i += 12 + length
+	}
+}
@@ -0,0 +1,227 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/h2non/bimg"
+)
+
+const (
+	placeholderSize        = 32
+	blurHashComponentsX    = 4
+	blurHashComponentsY    = 3
+	blurHashCharacters     = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+	dominantColorBucketBit = 4
+)
+
+// Placeholder is what a gallery paints while the photo itself loads. Colours
+// are "#rrggbb".
+type Placeholder struct {
+	BlurHash      string
+	DominantColor string
+	AverageColor  string
+}
+
+// NewPlaceholder computes the BlurHash and colours of buf from a 32x32
+// thumbnail. The dominant colour is the mean of the most populated bucket
+// after quantising each channel to 4 bits.
+func NewPlaceholder(buf []byte) (Placeholder, error) {
+	thumb, err := bimg.NewImage(buf).Process(bimg.Options{
+		Width:          placeholderSize,
+		Height:         placeholderSize,
+		Force:          true,
+		Interpretation: bimg.InterpretationSRGB,
+		Type:           bimg.PNG,
+		StripMetadata:  true,
+	})
+	if err != nil {
+		return Placeholder{}, err
+	}
+
+	img, err := png.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		return Placeholder{}, err
+	}
+
+	return Placeholder{
+		BlurHash:      blurHash(img, blurHashComponentsX, blurHashComponentsY),
+		DominantColor: dominantColor(img),
+		AverageColor:  averageColor(img),
+	}, nil
+}
+
+// blurHash encodes img following https://github.com/woltapp/blurhash.
+func blurHash(img image.Image, componentsX, componentsY int) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	factors := make([][3]float64, 0, componentsX*componentsY)
+
+	for j := 0; j < componentsY; j++ {
+		for i := 0; i < componentsX; i++ {
+			var factor [3]float64
+
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i*x)/float64(width)) * math.Cos(math.Pi*float64(j*y)/float64(height))
+					r, g, b := rgb8(img, bounds.Min.X+x, bounds.Min.Y+y)
+
+					factor[0] += basis * srgbToLinear(r)
+					factor[1] += basis * srgbToLinear(g)
+					factor[2] += basis * srgbToLinear(b)
+				}
+			}
+
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			scale := normalisation / float64(width*height)
+
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+
+	hash.WriteString(base83((componentsX-1)+(componentsY-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+
+	maxValue := 1.0
+
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = math.Max(actualMax, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+
+		quantisedMax := clamp(int(math.Floor(actualMax*166-0.5)), 0, 82)
+		maxValue = float64(quantisedMax+1) / 166
+
+		hash.WriteString(base83(quantisedMax, 1))
+	} else {
+		hash.WriteString(base83(0, 1))
+	}
+
+	hash.WriteString(base83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+
+	for _, f := range ac {
+		quantR := clamp(int(math.Floor(signPow(f[0]/maxValue, 0.5)*9+9.5)), 0, 18)
+		quantG := clamp(int(math.Floor(signPow(f[1]/maxValue, 0.5)*9+9.5)), 0, 18)
+		quantB := clamp(int(math.Floor(signPow(f[2]/maxValue, 0.5)*9+9.5)), 0, 18)
+
+		hash.WriteString(base83(quantR*19*19+quantG*19+quantB, 2))
+	}
+
+	return hash.String()
+}
+
+func averageColor(img image.Image) string {
+	bounds := img.Bounds()
+
+	var r, g, b, n int
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pr, pg, pb := rgb8(img, x, y)
+			r, g, b, n = r+pr, g+pg, b+pb, n+1
+		}
+	}
+
+	if n == 0 {
+		return ""
+	}
+
+	return hexColor(r/n, g/n, b/n)
+}
+
+func dominantColor(img image.Image) string {
+	type bucket struct {
+		r, g, b, n int
+	}
+
+	shift := 8 - dominantColorBucketBit
+	buckets := map[int]*bucket{}
+
+	var best *bucket
+
+	bounds := img.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b := rgb8(img, x, y)
+			key := (r>>shift)<<(2*dominantColorBucketBit) | (g>>shift)<<dominantColorBucketBit | b>>shift
+
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+
+			bk.r, bk.g, bk.b, bk.n = bk.r+r, bk.g+g, bk.b+b, bk.n+1
+
+			if best == nil || bk.n > best.n {
+				best = bk
+			}
+		}
+	}
+
+	if best == nil {
+		return ""
+	}
+
+	return hexColor(best.r/best.n, best.g/best.n, best.b/best.n)
+}
+
+func rgb8(img image.Image, x, y int) (int, int, int) {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return int(r >> 8), int(g >> 8), int(b >> 8)
+}
+
+func hexColor(r, g, b int) string {
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func srgbToLinear(value int) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+func clamp(value, lo, hi int) int {
+	return max(lo, min(value, hi))
+}
+
+func base83(value, length int) string {
+	out := make([]byte, length)
+
+	for i := length - 1; i >= 0; i-- {
+		out[i] = blurHashCharacters[value%83]
+		value /= 83
+	}
+
+	return string(out)
+}
@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testSplitImage paints the left three quarters of an 8x8 image c1 and the
+// rest c2.
+func testSplitImage(c1, c2 color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 6 {
+				img.Set(x, y, c1)
+			} else {
+				img.Set(x, y, c2)
+			}
+		}
+	}
+
+	return img
+}
+
+var (
+	testRed   = color.NRGBA{R: 255, A: 255}
+	testWhite = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	testBlack = color.NRGBA{A: 255}
+)
+
+func TestBlurHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		img         image.Image
+		componentsX int
+		componentsY int
+		want        string
+	}{
+		{name: "dc only", img: testSplitImage(testRed, testRed), componentsX: 1, componentsY: 1, want: "00TI:j"},
+		{name: "dc only white", img: testSplitImage(testWhite, testWhite), componentsX: 1, componentsY: 1, want: "00TSUA"},
+		{name: "solid", img: testSplitImage(testWhite, testWhite), componentsX: 4, componentsY: 3, want: "LfTSUA~qfQ~q~qt7fQt7fQfQfQfQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blurHash(tt.img, tt.componentsX, tt.componentsY); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlurHashLength(t *testing.T) {
+	img := testSplitImage(testBlack, testWhite)
+
+	for _, components := range [][2]int{{1, 1}, {4, 3}, {9, 9}} {
+		want := 4 + 2*components[0]*components[1]
+
+		if got := blurHash(img, components[0], components[1]); len(got) != want {
+			t.Errorf("%dx%d: got %d characters, want %d", components[0], components[1], len(got), want)
+		}
+	}
+}
+
+func TestBase83(t *testing.T) {
+	tests := []struct {
+		value  int
+		length int
+		want   string
+	}{
+		{value: 0, length: 1, want: "0"},
+		{value: 82, length: 1, want: "~"},
+		{value: 83, length: 2, want: "10"},
+		{value: 3429, length: 2, want: "fQ"},
+	}
+
+	for _, tt := range tests {
+		if got := base83(tt.value, tt.length); got != tt.want {
+			t.Errorf("base83(%d, %d): got %q, want %q", tt.value, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceholderColors(t *testing.T) {
+	img := testSplitImage(testBlack, testWhite)
+
+	if got := dominantColor(img); got != "#000000" {
+		t.Errorf("got dominant colour %q, want %q", got, "#000000")
+	}
+
+	if got := averageColor(img); got != "#3f3f3f" {
+		t.Errorf("got average colour %q, want %q", got, "#3f3f3f")
+	}
+}
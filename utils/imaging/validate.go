@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/h2non/bimg"
+	"github.com/notblessy/ekspresi-core/model"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatHEIC = "heic"
+	FormatAVIF = "avif"
+)
+
+// Limits bounds an upload. Zero values are unlimited.
+type Limits struct {
+	MaxBytes     int64
+	MaxDimension int
+}
+
+// Validate sniffs the real format of buf and checks it against limits. It
+// returns the detected format, or an error wrapping one of the model upload
+// errors.
+func Validate(buf []byte, limits Limits) (string, error) {
+	if limits.MaxBytes > 0 && int64(len(buf)) > limits.MaxBytes {
+		return "", fmt.Errorf("%w: %d bytes exceeds %d", model.ErrFileTooLarge, len(buf), limits.MaxBytes)
+	}
+
+	format := Sniff(buf)
+	if format == "" {
+		return "", model.ErrUnsupportedMediaType
+	}
+
+	if !isComplete(buf, format) {
+		return "", fmt.Errorf("%w: truncated %s", model.ErrCorruptImage, format)
+	}
+
+	size, err := bimg.NewImage(buf).Size()
+	if err != nil || size.Width == 0 || size.Height == 0 {
+		return "", fmt.Errorf("%w: cannot decode %s", model.ErrCorruptImage, format)
+	}
+
+	if limits.MaxDimension > 0 && (size.Width > limits.MaxDimension || size.Height > limits.MaxDimension) {
+		return "", fmt.Errorf("%w: %dx%d exceeds %dpx", model.ErrImageTooLarge, size.Width, size.Height, limits.MaxDimension)
+	}
+
+	return format, nil
+}
+
+// Sniff detects the image format from magic bytes, ignoring any client
+// supplied content type. It returns an empty string for unsupported data.
+func Sniff(buf []byte) string {
+	switch {
+	case bytes.HasPrefix(buf, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG
+	case bytes.HasPrefix(buf, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case len(buf) >= 12 && string(buf[:4]) == "RIFF" && string(buf[8:12]) == "WEBP":
+		return FormatWebP
+	case len(buf) >= 12 && string(buf[4:8]) == "ftyp":
+		switch string(buf[8:12]) {
+		case "avif", "avis":
+			return FormatAVIF
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return FormatHEIC
+		}
+	}
+
+	return ""
+}
+
+// isComplete checks the container structure for truncation.
+func isComplete(buf []byte, format string) bool {
+	switch format {
+	case FormatJPEG:
+		return bytes.HasSuffix(bytes.TrimRight(buf, "\x00"), []byte{0xFF, 0xD9})
+	case FormatPNG:
+		return len(buf) >= 12 && string(buf[len(buf)-8:len(buf)-4]) == "IEND"
+	case FormatWebP:
+		return int(binary.LittleEndian.Uint32(buf[4:8]))+8 <= len(buf)
+	default:
+		return isoBoxesComplete(buf)
+	}
+}
+
+// isoBoxesComplete walks the top-level ISO BMFF boxes of HEIC and AVIF files.
+func isoBoxesComplete(buf []byte) bool {
+	for offset := uint64(0); offset < uint64(len(buf)); {
+		if offset+8 > uint64(len(buf)) {
+			return false
+		}
+
+		size := uint64(binary.BigEndian.Uint32(buf[offset:]))
+
+		switch size {
+		case 0:
+			return true
+		case 1:
+			if offset+16 > uint64(len(buf)) {
+				return false
+			}
+
+			size = binary.BigEndian.Uint64(buf[offset+8:])
+		}
+
+		if size < 8 {
+			return false
+		}
+
+		offset += size
+		if offset > uint64(len(buf)) {
+			return false
+		}
+	}
+
+	return true
+}
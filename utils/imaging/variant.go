@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/h2non/bimg"
+)
+
+const (
+	DefaultVariants = "thumb:320,medium:1024,large:2048,original:0"
+	DefaultFormats  = "webp"
+	DefaultQuality  = 80
+)
+
+var formats = map[string]bimg.ImageType{
+	"webp": bimg.WEBP,
+	"avif": bimg.AVIF,
+	"jpeg": bimg.JPEG,
+}
+
+// VariantSpec names a rendition and its maximum width. A zero width keeps the
+// original dimensions.
+type VariantSpec struct {
+	Name  string
+	Width int
+}
+
+// Variant is an encoded rendition of an image.
+type Variant struct {
+	Name        string
+	Format      string
+	Width       int
+	Height      int
+	Watermarked bool
+	Data        []byte
+}
+
+// Pipeline renders every variant spec in every format.
+type Pipeline struct {
+	Specs   []VariantSpec
+	Formats []string
+	Quality int
+}
+
+// NewPipeline parses variant specs such as "thumb:320,original:0" and a
+// comma separated list of output formats.
+func NewPipeline(variants, outputFormats string, quality int) (*Pipeline, error) {
+	var specs []VariantSpec
+
+	for _, v := range strings.Split(variants, ",") {
+		name, width, ok := strings.Cut(strings.TrimSpace(v), ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variant spec %q", v)
+		}
+
+		w, err := strconv.Atoi(width)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid variant width %q", v)
+		}
+
+		specs = append(specs, VariantSpec{Name: name, Width: w})
+	}
+
+	var names []string
+
+	for _, f := range strings.Split(outputFormats, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if _, ok := formats[f]; !ok {
+			return nil, fmt.Errorf("unsupported variant format %q", f)
+		}
+
+		names = append(names, f)
+	}
+
+	return &Pipeline{
+		Specs:   specs,
+		Formats: names,
+		Quality: quality,
+	}, nil
+}
+
+// Process renders all variants of buf, stamping watermark on the variants it
+// applies to. Images are never enlarged, so a spec wider than the source
+// yields the source dimensions.
+func (p *Pipeline) Process(buf []byte, watermark *Watermark) ([]Variant, error) {
+	size, err := bimg.NewImage(buf).Size()
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []Variant
+
+	for _, spec := range p.Specs {
+		width, height := size.Width, size.Height
+		if spec.Width > 0 && spec.Width < size.Width {
+			width, height = spec.Width, size.Height*spec.Width/size.Width
+		}
+
+		var mark bimg.WatermarkImage
+
+		watermarked := watermark.appliesTo(spec.Name)
+		if watermarked {
+			if mark, err = watermark.options(width, height); err != nil {
+				return nil, fmt.Errorf("watermark %s: %w", spec.Name, err)
+			}
+		}
+
+		for _, format := range p.Formats {
+			options := bimg.Options{
+				Type:           formats[format],
+				Quality:        p.Quality,
+				StripMetadata:  true,
+				WatermarkImage: mark,
+			}
+
+			if width != size.Width {
+				options.Width = width
+			}
+
+			data, err := bimg.NewImage(buf).Process(options)
+			if err != nil {
+				return nil, fmt.Errorf("render %s/%s: %w", spec.Name, format, err)
+			}
+
+			rendered, err := bimg.NewImage(data).Size()
+			if err != nil {
+				return nil, err
+			}
+
+			variants = append(variants, Variant{
+				Name:        spec.Name,
+				Format:      format,
+				Width:       rendered.Width,
+				Height:      rendered.Height,
+				Watermarked: watermarked,
+				Data:        data,
+			})
+		}
+	}
+
+	return variants, nil
+}
@@ -0,0 +1,134 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	"github.com/h2non/bimg"
+)
+
+const (
+	defaultWatermarkScale   = 0.2
+	defaultWatermarkOpacity = 0.5
+	watermarkFont           = "sans bold 12"
+	watermarkFontPoints     = 12
+)
+
+// Watermark is stamped onto the variants named in Variants, or all of them
+// when Variants is empty. Logo takes precedence over Text.
+type Watermark struct {
+	Text     string
+	Logo     []byte
+	Position string
+	Opacity  float32
+	Scale    float64
+	Variants []string
+}
+
+func (w *Watermark) appliesTo(variant string) bool {
+	if w == nil || (w.Text == "" && len(w.Logo) == 0) {
+		return false
+	}
+
+	if len(w.Variants) == 0 {
+		return true
+	}
+
+	for _, v := range w.Variants {
+		if v == variant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// options places the watermark on an image of the given output size.
+func (w *Watermark) options(width, height int) (bimg.WatermarkImage, error) {
+	scale := w.Scale
+	if scale <= 0 {
+		scale = defaultWatermarkScale
+	}
+
+	opacity := w.Opacity
+	if opacity <= 0 {
+		opacity = defaultWatermarkOpacity
+	}
+
+	markWidth := max(int(float64(width)*scale), 1)
+
+	mark, err := w.render(markWidth)
+	if err != nil {
+		return bimg.WatermarkImage{}, err
+	}
+
+	size, err := bimg.NewImage(mark).Size()
+	if err != nil {
+		return bimg.WatermarkImage{}, err
+	}
+
+	left, top := placeWatermark(w.Position, width, height, size.Width, size.Height)
+
+	return bimg.WatermarkImage{
+		Left:    left,
+		Top:     top,
+		Buf:     mark,
+		Opacity: opacity,
+	}, nil
+}
+
+// placeWatermark returns the top-left corner of a markWidth x markHeight
+// watermark at position on a width x height image, inset by a 2% margin.
+// Unknown positions fall back to the bottom right.
+func placeWatermark(position string, width, height, markWidth, markHeight int) (int, int) {
+	margin := width / 50
+	left, top := margin, margin
+
+	switch position {
+	case "top_right":
+		left = width - markWidth - margin
+	case "bottom_left":
+		top = height - markHeight - margin
+	case "center":
+		left = (width - markWidth) / 2
+		top = (height - markHeight) / 2
+	case "top_left":
+	default:
+		left = width - markWidth - margin
+		top = height - markHeight - margin
+	}
+
+	return max(left, 0), max(top, 0)
+}
+
+// render returns the watermark as a PNG markWidth pixels wide.
+func (w *Watermark) render(markWidth int) ([]byte, error) {
+	if len(w.Logo) > 0 {
+		return bimg.NewImage(w.Logo).Process(bimg.Options{
+			Width: markWidth,
+			Type:  bimg.PNG,
+		})
+	}
+
+	// Size the font so the text roughly fills markWidth, assuming glyphs
+	// about 0.6em wide.
+	fontPixels := max(int(float64(markWidth)/(0.6*float64(len([]rune(w.Text))))), 8)
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, markWidth, fontPixels*3/2))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, err
+	}
+
+	return bimg.NewImage(buf.Bytes()).Watermark(bimg.Watermark{
+		Text:        w.Text,
+		Font:        watermarkFont,
+		Width:       markWidth,
+		DPI:         fontPixels * 72 / watermarkFontPoints,
+		Opacity:     1,
+		NoReplicate: true,
+		Background:  bimg.Color{R: 255, G: 255, B: 255},
+	})
+}
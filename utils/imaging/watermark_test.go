@@ -0,0 +1,34 @@
+package imaging
+
+import "testing"
+
+func TestPlaceWatermark(t *testing.T) {
+	tests := []struct {
+		position string
+		left     int
+		top      int
+	}{
+		{position: "top_left", left: 20, top: 20},
+		{position: "top_right", left: 780, top: 20},
+		{position: "bottom_left", left: 20, top: 480},
+		{position: "bottom_right", left: 780, top: 480},
+		{position: "center", left: 400, top: 250},
+		{position: "", left: 780, top: 480},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.position, func(t *testing.T) {
+			left, top := placeWatermark(tt.position, 1000, 600, 200, 100)
+			if left != tt.left || top != tt.top {
+				t.Errorf("got (%d, %d), want (%d, %d)", left, top, tt.left, tt.top)
+			}
+		})
+	}
+}
+
+func TestPlaceWatermarkOversized(t *testing.T) {
+	left, top := placeWatermark("bottom_right", 100, 50, 300, 80)
+	if left != 0 || top != 0 {
+		t.Errorf("got (%d, %d), want the mark clamped to (0, 0)", left, top)
+	}
+}
@@ -0,0 +1,230 @@
+// Package video inspects clips with ffprobe and grabs poster frames with
+// ffmpeg. Both binaries must be on PATH.
+package video
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+)
+
+const (
+	FormatMP4  = "mp4"
+	FormatWebM = "webm"
+	FormatMOV  = "mov"
+)
+
+// Limits bounds a clip. Zero values are unlimited.
+type Limits struct {
+	MaxBytes    int64
+	MaxDuration time.Duration
+}
+
+// Info describes a clip as displayed, so rotated phone footage reports its
+// upright dimensions.
+type Info struct {
+	Format   string
+	Width    int
+	Height   int
+	Duration time.Duration
+}
+
+// Sniff detects the container from magic bytes. It returns an empty string
+// for anything that is not a supported clip.
+func Sniff(head []byte) string {
+	switch {
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		switch string(head[8:12]) {
+		case "qt  ":
+			return FormatMOV
+		case "isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "M4V ", "dash":
+			return FormatMP4
+		}
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return FormatWebM
+	}
+
+	return ""
+}
+
+// Validate checks the clip at path against limits. It returns the probed
+// clip, or an error wrapping one of the model upload errors.
+func Validate(ctx context.Context, path string, limits Limits) (Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+
+	if limits.MaxBytes > 0 && stat.Size() > limits.MaxBytes {
+		return Info{}, fmt.Errorf("%w: %d bytes exceeds %d", model.ErrFileTooLarge, stat.Size(), limits.MaxBytes)
+	}
+
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(file, head)
+
+	format := Sniff(head[:n])
+	if format == "" {
+		return Info{}, model.ErrUnsupportedMediaType
+	}
+
+	info, err := Probe(ctx, path)
+	if err != nil {
+		return Info{}, fmt.Errorf("%w: %s", model.ErrCorruptVideo, err.Error())
+	}
+
+	info.Format = format
+
+	if limits.MaxDuration > 0 && info.Duration > limits.MaxDuration {
+		return Info{}, fmt.Errorf("%w: %s exceeds %s", model.ErrVideoTooLong, info.Duration.Round(time.Second), limits.MaxDuration)
+	}
+
+	return info, nil
+}
+
+type probeOutput struct {
+	Streams []struct {
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// Probe reads the dimensions and duration of the first video stream.
+func Probe(ctx context.Context, path string) (Info, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:stream_tags=rotate:stream_side_data=rotation:format=duration",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return Info{}, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	return parseProbe(out)
+}
+
+// parseProbe reads the JSON printed by ffprobe in Probe.
+func parseProbe(out []byte) (Info, error) {
+	var probe probeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return Info{}, err
+	}
+
+	if len(probe.Streams) == 0 || probe.Streams[0].Width == 0 || probe.Streams[0].Height == 0 {
+		return Info{}, fmt.Errorf("no video stream")
+	}
+
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil || seconds <= 0 {
+		return Info{}, fmt.Errorf("unknown duration")
+	}
+
+	stream := probe.Streams[0]
+
+	rotation, _ := strconv.ParseFloat(stream.Tags["rotate"], 64)
+	for _, side := range stream.SideDataList {
+		if side.Rotation != 0 {
+			rotation = side.Rotation
+		}
+	}
+
+	info := Info{
+		Width:    stream.Width,
+		Height:   stream.Height,
+		Duration: time.Duration(seconds * float64(time.Second)),
+	}
+
+	if int(math.Abs(rotation))%180 == 90 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+
+	return info, nil
+}
+
+// StripMetadata remuxes the clip at path without its container and stream
+// metadata, such as the GPS position phones record, into a temporary file
+// whose path it returns. Streams are copied, not re-encoded. The caller
+// removes the file.
+func StripMetadata(ctx context.Context, path, format string) (string, error) {
+	out, err := os.CreateTemp("", "ekspresi-video-*."+format)
+	if err != nil {
+		return "", err
+	}
+	out.Close()
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-y",
+		"-i", path,
+		"-map", "0",
+		"-map_metadata", "-1",
+		"-map_chapters", "-1",
+		"-c", "copy",
+		"-f", format,
+		out.Name(),
+	)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("ffmpeg: %w: %s", err, stderr.String())
+	}
+
+	return out.Name(), nil
+}
+
+// Poster grabs the frame at one second, or the middle of shorter clips, as
+// a JPEG.
+func Poster(ctx context.Context, path string, duration time.Duration) ([]byte, error) {
+	at := min(time.Second, duration/2)
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-ss", strconv.FormatFloat(at.Seconds(), 'f', 3, 64),
+		"-i", path,
+		"-frames:v", "1",
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"-q:v", "3",
+		"pipe:1",
+	)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, stderr.String())
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("ffmpeg: no frame at %s", at)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package video
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{name: "mp4", head: []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00"), want: FormatMP4},
+		{name: "m4v", head: []byte("\x00\x00\x00\x1cftypM4V \x00\x00\x00\x01"), want: FormatMP4},
+		{name: "mov", head: []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), want: FormatMOV},
+		{name: "webm", head: []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81"), want: FormatWebM},
+		{name: "heic", head: []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00")},
+		{name: "jpeg", head: []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01")},
+		{name: "truncated", head: []byte("\x00\x00\x00\x20ftyp")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sniff(tt.head); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    Info
+		wantErr bool
+	}{
+		{
+			name: "landscape",
+			out:  `{"streams":[{"width":1920,"height":1080}],"format":{"duration":"12.500000"}}`,
+			want: Info{Width: 1920, Height: 1080, Duration: 12500 * time.Millisecond},
+		},
+		{
+			name: "rotate tag",
+			out:  `{"streams":[{"width":1920,"height":1080,"tags":{"rotate":"90"}}],"format":{"duration":"3"}}`,
+			want: Info{Width: 1080, Height: 1920, Duration: 3 * time.Second},
+		},
+		{
+			name: "display matrix",
+			out:  `{"streams":[{"width":1920,"height":1080,"side_data_list":[{"rotation":-90}]}],"format":{"duration":"3"}}`,
+			want: Info{Width: 1080, Height: 1920, Duration: 3 * time.Second},
+		},
+		{
+			name: "upside down",
+			out:  `{"streams":[{"width":1920,"height":1080,"side_data_list":[{"rotation":180}]}],"format":{"duration":"3"}}`,
+			want: Info{Width: 1920, Height: 1080, Duration: 3 * time.Second},
+		},
+		{name: "no stream", out: `{"streams":[],"format":{"duration":"3"}}`, wantErr: true},
+		{name: "no duration", out: `{"streams":[{"width":640,"height":480}],"format":{"duration":"N/A"}}`, wantErr: true},
+		{name: "not json", out: `ffprobe crashed`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProbe([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
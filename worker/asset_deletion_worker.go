@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// AssetDeletionMetrics counts drained deletions, published under
+// "asset_deletions" in expvar.
+var AssetDeletionMetrics = expvar.NewMap("asset_deletions")
+
+// AssetDeletionWorker drains the asset deletion queue.
+type AssetDeletionWorker struct {
+	repo      model.AssetDeletionRepository
+	interval  time.Duration
+	batchSize int
+}
+
+func NewAssetDeletionWorker(repo model.AssetDeletionRepository, interval time.Duration, batchSize int) *AssetDeletionWorker {
+	return &AssetDeletionWorker{
+		repo:      repo,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Start runs the worker until ctx is done. Each tick drains batches until the
+// queue has nothing due.
+func (w *AssetDeletionWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				result, err := w.repo.Drain(ctx, w.batchSize)
+				if err != nil {
+					AssetDeletionMetrics.Add("errors", 1)
+					logrus.WithError(err).Error("failed to drain asset deletions")
+					break
+				}
+
+				AssetDeletionMetrics.Add("deleted", int64(result.Deleted))
+				AssetDeletionMetrics.Add("retried", int64(result.Retried))
+				AssetDeletionMetrics.Add("dead", int64(result.Dead))
+
+				if result.Dead > 0 {
+					logrus.WithField("dead", result.Dead).Error("asset deletions moved to dead letter")
+				}
+
+				if result.Deleted+result.Retried+result.Dead < w.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// AssetReconcileWorker periodically reconciles storage with photo rows and
+// drops the cached feeds listing deleted rows.
+type AssetReconcileWorker struct {
+	reconciler model.AssetReconciler
+	feeds      model.FeedInvalidator
+	interval   time.Duration
+	dryRun     bool
+}
+
+func NewAssetReconcileWorker(reconciler model.AssetReconciler, feeds model.FeedInvalidator, interval time.Duration, dryRun bool) *AssetReconcileWorker {
+	return &AssetReconcileWorker{
+		reconciler: reconciler,
+		feeds:      feeds,
+		interval:   interval,
+		dryRun:     dryRun,
+	}
+}
+
+// Start runs the reconciler until ctx is done.
+func (w *AssetReconcileWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := w.reconciler.Reconcile(ctx, w.dryRun)
+			if err != nil {
+				logrus.WithError(err).Error("failed to reconcile assets")
+				continue
+			}
+
+			for _, userID := range report.UserIDs {
+				w.feeds.InvalidateFeeds(userID)
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"dry_run":        report.DryRun,
+				"orphan_assets":  len(report.OrphanAssets),
+				"missing_assets": len(report.MissingAssets),
+				"deleted_assets": report.DeletedAssets,
+				"deleted_rows":   report.DeletedRows,
+			}).Info("reconciled assets")
+		}
+	}
+}
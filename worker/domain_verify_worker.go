@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// DomainVerifyWorker checks pending custom domains and deactivates those
+// whose owner lost the entitlement.
+type DomainVerifyWorker struct {
+	verifier  model.DomainVerifier
+	interval  time.Duration
+	batchSize int
+}
+
+func NewDomainVerifyWorker(verifier model.DomainVerifier, interval time.Duration, batchSize int) *DomainVerifyWorker {
+	return &DomainVerifyWorker{
+		verifier:  verifier,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Start runs the worker until ctx is done.
+func (w *DomainVerifyWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deactivated, err := w.verifier.DeactivateIneligible(ctx)
+			if err != nil {
+				logrus.WithError(err).Error("failed to deactivate custom domains")
+			} else if deactivated > 0 {
+				logrus.WithField("deactivated", deactivated).Info("deactivated custom domains")
+			}
+
+			verified, err := w.verifier.VerifyPending(ctx, w.batchSize)
+			if err != nil {
+				logrus.WithError(err).Error("failed to verify pending domains")
+				continue
+			}
+
+			if verified > 0 {
+				logrus.WithField("verified", verified).Info("verified custom domains")
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// UploadSessionCleaner removes abandoned resumable uploads.
+type UploadSessionCleaner struct {
+	repo     model.UploadSessionRepository
+	interval time.Duration
+}
+
+func NewUploadSessionCleaner(repo model.UploadSessionRepository, interval time.Duration) *UploadSessionCleaner {
+	return &UploadSessionCleaner{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Start runs the cleaner until ctx is done.
+func (w *UploadSessionCleaner) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := w.repo.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				logrus.WithError(err).Error("failed to delete expired upload sessions")
+				continue
+			}
+
+			if deleted > 0 {
+				logrus.WithField("deleted", deleted).Info("deleted expired upload sessions")
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/notblessy/ekspresi-core/model"
+	"github.com/sirupsen/logrus"
+)
+
+// VariantRenderWorker re-renders photo variants after watermark changes and
+// drops the cached feeds that point at the old ones.
+type VariantRenderWorker struct {
+	renderer  model.VariantRenderer
+	feeds     model.FeedInvalidator
+	interval  time.Duration
+	batchSize int
+}
+
+func NewVariantRenderWorker(renderer model.VariantRenderer, feeds model.FeedInvalidator, interval time.Duration, batchSize int) *VariantRenderWorker {
+	return &VariantRenderWorker{
+		renderer:  renderer,
+		feeds:     feeds,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Start runs the worker until ctx is done.
+func (w *VariantRenderWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rendered, err := w.renderer.RenderPending(ctx, w.batchSize)
+			if err != nil {
+				logrus.WithError(err).Error("failed to render pending photos")
+				continue
+			}
+
+			owners := map[string]bool{}
+			for _, photo := range rendered {
+				owners[photo.UserID] = true
+			}
+
+			for userID := range owners {
+				w.feeds.InvalidateFeeds(userID)
+			}
+
+			if len(rendered) > 0 {
+				logrus.WithField("rendered", len(rendered)).Info("rendered photo variants")
+			}
+		}
+	}
+}